@@ -33,6 +33,28 @@ func main() {
 			Name:  "xpub",
 			Usage: "destination account extended public key",
 		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
+		},
 		cli.UintFlag{
 			Name:  "from",
 			Usage: "start account number",
@@ -40,12 +62,23 @@ func main() {
 		},
 		cli.UintFlag{
 			Name:  "until",
-			Usage: "final account number",
+			Usage: "final account number (scan until gap limit if omitted)",
+		},
+		cli.UintFlag{
+			Name:  "gap",
+			Usage: "number of consecutive unused accounts to stop scanning at",
+			Value: 20,
 		},
 	}
 
 	app.Action = func(ctx *cli.Context) error {
-		rpc, xpub, from, until, err := parseFlags(ctx)
+		rpc, input, from, until, gap, err := parseFlags(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Init keychain
+		keychain, err := input.Keychain()
 		if err != nil {
 			return err
 		}
@@ -63,14 +96,8 @@ func main() {
 			return err
 		}
 
-		// Init keychain
-		keychain, err := pkg.New(xpub)
-		if err != nil {
-			return err
-		}
-
 		// Get balances
-		result, err := manager.GetBalances(keychain, from, until)
+		result, err := manager.GetBalances(keychain, from, until, gap)
 		if err != nil {
 			return err
 		}
@@ -89,27 +116,37 @@ func main() {
 	}
 }
 
-func parseFlags(ctx *cli.Context) (string, string, uint, uint, error) {
+func parseFlags(ctx *cli.Context) (string, pkg.KeychainInput, uint, uint, uint, error) {
 	// Parse CLI flags
 	rpc := ctx.String("rpc")
 	if len(rpc) == 0 {
-		return "", "", 0, 0, errors.New("Please provide RPC URL using --rpc flag")
+		return "", pkg.KeychainInput{}, 0, 0, 0, errors.New("Please provide RPC URL using --rpc flag")
+	}
+
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xpub"),
+		KeyType:        pkg.KeyPublic,
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
 	}
 
-	xpub := ctx.String("xpub")
-	if len(xpub) == 0 {
-		return "", "", 0, 0, errors.New("Please provide account extended public key using --xpub flag")
+	if input.IsEmpty() {
+		return "", pkg.KeychainInput{}, 0, 0, 0, errors.New("Please provide account extended public key using --xpub or --mnemonic flag")
 	}
 
 	from := ctx.Uint("from")
 	until := ctx.Uint("until")
-	if until == 0 {
-		return "", "", 0, 0, errors.New("Please provide account scan limit with --until flag")
+	gap := ctx.Uint("gap")
+	if until == 0 && gap == 0 {
+		return "", pkg.KeychainInput{}, 0, 0, 0, errors.New("Please provide account scan limit with --until or --gap flag")
 	}
 
-	if from > until {
-		return "", "", 0, 0, errors.New("From should be greater than until")
+	if until > 0 && from > until {
+		return "", pkg.KeychainInput{}, 0, 0, 0, errors.New("From should be greater than until")
 	}
 
-	return rpc, xpub, from, until, nil
+	return rpc, input, from, until, gap, nil
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "broadcast"
+	app.Usage = "submits signed transaction bundle"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "wei",
+			Usage: "output values in wei",
+		},
+		cli.StringFlag{
+			Name:  "rpc",
+			Usage: "Ethereum node RPC URL",
+		},
+		cli.StringFlag{
+			Name:  "bundle",
+			Usage: "signed transaction bundle file",
+		},
+		cli.BoolFlag{
+			Name:  "wait",
+			Usage: "wait for transaction receipts and print report",
+		},
+		cli.Uint64Flag{
+			Name:  "confirmations",
+			Usage: "number of confirmations to wait for (with --wait)",
+			Value: 1,
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "maximum time to wait for receipts (with --wait)",
+			Value: 10 * time.Minute,
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Parse CLI flags
+		rpc := ctx.String("rpc")
+		if len(rpc) == 0 {
+			return errors.New("Please provide RPC URL using --rpc flag")
+		}
+
+		path := ctx.String("bundle")
+		if len(path) == 0 {
+			return errors.New("Please provide signed transaction bundle using --bundle flag")
+		}
+
+		// Read bundle
+		bundle, err := pkg.ReadBundle(path)
+		if err != nil {
+			return err
+		}
+
+		if !bundle.Signed() {
+			return errors.New("Bundle is not signed")
+		}
+
+		// Init manager
+		wei := ctx.Bool("wei")
+		manager, err := pkg.NewManager(rpc, bundle.ChainID.Uint64(), 0, wei)
+		if err != nil {
+			return err
+		}
+
+		if err := manager.CheckChainID(); err != nil {
+			return err
+		}
+
+		if ctx.Bool("wait") {
+			if ctx.Uint64("confirmations") == 0 {
+				return errors.New("Please provide at least one confirmation using --confirmations flag")
+			}
+
+			manager.Confirmations = ctx.Uint64("confirmations")
+			manager.WaitTimeout = ctx.Duration("timeout")
+		}
+
+		// Broadcast
+		bundle.Print(wei)
+		fmt.Println()
+		total, value, err := manager.SendBundle(bundle)
+		units := pkg.Units(wei)
+		sent := pkg.WeiOrEther(value, wei)
+		fmt.Printf("Sent %d transactions of %d, total %s %s\n", total, len(bundle.Transactions), sent.String(), units)
+		if waitErr := manager.WaitBundle(bundle, total); err == nil {
+			err = waitErr
+		}
+
+		return err
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/pavel-main/ethereum-hd-tools/pkg"
@@ -37,9 +38,87 @@ func main() {
 			Usage: "custom gas price (in gwei)",
 			Value: 0,
 		},
+		cli.StringFlag{
+			Name:  "gas-strategy",
+			Usage: "gas price strategy without --fee: node, percentile:PERCENT[:BLOCKS], fixed:GWEI or multiplier:FACTOR",
+			Value: "node",
+		},
+		cli.StringFlag{
+			Name:  "max-fee",
+			Usage: "abort if network gas price (base fee plus priority fee of dynamic fee transactions) exceeds it (in gwei)",
+		},
+		cli.StringFlag{
+			Name:  "max-total-fees",
+			Usage: "abort if maximum fees of all transactions exceed it (in ETH)",
+		},
+		cli.Float64Flag{
+			Name:  "gas-multiplier",
+			Usage: "safety multiplier of estimated gas limits above 21000 (transfers to contracts)",
+			Value: pkg.DefaultGasMultiplier,
+		},
+		cli.BoolFlag{
+			Name:  "eip1559",
+			Usage: "send dynamic fee (EIP-1559) transactions with fees suggested from recent blocks",
+		},
+		cli.StringFlag{
+			Name:  "tip",
+			Usage: "max priority fee per gas of dynamic fee transactions (in gwei), implies --eip1559",
+		},
+		cli.StringFlag{
+			Name:  "fee-cap",
+			Usage: "max fee per gas of dynamic fee transactions (in gwei), implies --eip1559",
+		},
 		cli.StringFlag{
 			Name:  "xprv",
-			Usage: "source account extended private key",
+			Usage: "source account extended private key (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.StringFlag{
+			Name:  "xpub",
+			Usage: "source account extended public key (with --signer or --prepare)",
+		},
+		cli.StringFlag{
+			Name:  "signer",
+			Usage: "remote Clef-style signer URL or unix socket path (defaults to $HD_AGENT_SOCK without local key)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "key-name",
+			Usage: "name of extended private key or mnemonic in vault",
+		},
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "vault-password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
 		},
 		cli.UintFlag{
 			Name:  "from",
@@ -48,26 +127,83 @@ func main() {
 		},
 		cli.UintFlag{
 			Name:  "until",
-			Usage: "final account number",
-			Value: 0,
+			Usage: "final account number (scan until gap limit if omitted)",
+		},
+		cli.UintFlag{
+			Name:  "gap",
+			Usage: "number of consecutive unused accounts to stop scanning at",
+			Value: 20,
 		},
 		cli.StringFlag{
 			Name:  "amount",
 			Usage: "desired amount (in ETH)",
 		},
+		cli.StringFlag{
+			Name:  "prepare",
+			Usage: "write unsigned transaction bundle to file for offline signing instead of sending",
+		},
+		cli.StringFlag{
+			Name:  "dry-run",
+			Usage: "sign transactions and write them to file instead of sending (send later with broadcast command)",
+		},
 		cli.StringFlag{
 			Name:  "destination",
 			Usage: "destination address",
 		},
+		cli.StringFlag{
+			Name:  "journal",
+			Usage: "journal directory (default: ~/.ethereum-hd-tools/journal)",
+		},
+		cli.StringFlag{
+			Name:  "resume",
+			Usage: "resume interrupted operation from journal file, sending only outstanding transactions",
+		},
+		cli.BoolFlag{
+			Name:  "allow-pending",
+			Usage: "proceed even if addresses have pending transactions not sent by this tool",
+		},
+		cli.BoolFlag{
+			Name:  "wait",
+			Usage: "wait for transaction receipts and print report",
+		},
+		cli.Uint64Flag{
+			Name:  "confirmations",
+			Usage: "number of confirmations to wait for (with --wait)",
+			Value: 1,
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "maximum time to wait for receipts (with --wait)",
+			Value: 10 * time.Minute,
+		},
 	}
 
 	app.Action = func(ctx *cli.Context) error {
+		if path := ctx.String("resume"); len(path) > 0 {
+			return resume(ctx, path)
+		}
+
 		// Parse CLI flags
-		rpc, xprv, from, until, dest, amount, err := parseFlags(ctx)
+		rpc, input, url, from, until, gap, dest, amount, err := parseFlags(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Init keychain
+		keychain, err := input.Keychain()
 		if err != nil {
 			return err
 		}
 
+		// Init signer
+		var signer pkg.Signer = pkg.NewHDSigner(keychain, uint32(from), uint32(until))
+		if len(url) > 0 {
+			signer, err = pkg.NewRemoteSigner(url)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Init manager
 		chain := ctx.Uint64("chain")
 		fee := ctx.Uint64("fee")
@@ -77,19 +213,38 @@ func main() {
 			return err
 		}
 
-		// Set gas price
-		if err := manager.SetGasPrice(); err != nil {
+		manager.DryRun = ctx.String("dry-run")
+		manager.Nonces.AllowPending = ctx.Bool("allow-pending")
+		manager.JournalDir = ctx.String("journal")
+		if len(manager.JournalDir) == 0 {
+			manager.JournalDir = pkg.DefaultJournalDir()
+		}
+
+		manager.GasMultiplier = ctx.Float64("gas-multiplier")
+		if manager.GasMultiplier < 1 {
+			return errors.New("Gas multiplier should not be less than 1")
+		}
+
+		if err := setWait(ctx, manager); err != nil {
 			return err
 		}
 
-		// Init keychain
-		keychain, err := pkg.New(xprv)
-		if err != nil {
+		if err := setDynamicFee(ctx, manager); err != nil {
+			return err
+		}
+
+		if err := setGasStrategy(ctx, manager); err != nil {
+			return err
+		}
+
+		// Set gas price
+		if err := manager.SetGasPrice(); err != nil {
 			return err
 		}
 
 		// Get balance
-		result, err := manager.GetBalancesUntil(keychain, amount, from, until)
+		destination := common.HexToAddress(dest)
+		result, err := manager.GetBalancesUntil(keychain, amount, destination, from, until, gap)
 		if err != nil {
 			return err
 		}
@@ -98,8 +253,29 @@ func main() {
 			return errors.New("No funds available (for selected accounts)")
 		}
 
+		// Write unsigned bundle for offline signing
+		if prepare := ctx.String("prepare"); len(prepare) > 0 {
+			bundle, err := manager.PlanCollect(keychain, result, destination)
+			if err != nil {
+				return err
+			}
+
+			bundle.Print(wei)
+			if err := bundle.WriteFile(prepare); err != nil {
+				return err
+			}
+
+			fmt.Printf("Unsigned bundle written to %s\n", prepare)
+			return nil
+		}
+
+		// Sign without sending, nothing to confirm
+		if len(manager.DryRun) > 0 {
+			_, err := manager.Collect(signer, keychain, result, destination)
+			return err
+		}
+
 		// Confirmation window
-		destination := common.HexToAddress(dest)
 		result.PrintConfirmation(destination, amount, wei)
 
 		// Scan for input
@@ -108,7 +284,7 @@ func main() {
 		if scanner.Text() == "yes" {
 			fmt.Println()
 
-			total, err := manager.Collect(keychain, result, destination)
+			total, err := manager.Collect(signer, keychain, result, destination)
 			units := pkg.Units(wei)
 			sent := pkg.WeiOrEther(total, wei)
 			fmt.Printf("Total sent: %s %s\n", sent.String(), units)
@@ -125,49 +301,225 @@ func main() {
 	}
 }
 
-func parseFlags(ctx *cli.Context) (string, string, uint, uint, string, *big.Int, error) {
+func parseFlags(ctx *cli.Context) (string, pkg.KeychainInput, string, uint, uint, uint, string, *big.Int, error) {
 	rpc := ctx.String("rpc")
 	if len(rpc) == 0 {
-		return "", "", 0, 0, "", nil, errors.New("Please provide RPC URL using --rpc flag")
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide RPC URL using --rpc flag")
 	}
 
-	xprv := ctx.String("xprv")
-	if len(xprv) == 0 {
-		return "", "", 0, 0, "", nil, errors.New("Please provide account extended private key using --xprv flag")
+	input := keychainInput(ctx)
+
+	// Remote signer and offline signing hold private keys elsewhere, so extended public key is enough
+	url := ""
+	prepare := len(ctx.String("prepare")) > 0
+	if !prepare {
+		url = pkg.SignerURL(ctx.String("signer"), !input.IsEmpty())
+	}
+
+	if len(url) > 0 || prepare {
+		if len(input.Key) > 0 {
+			return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide extended public key using --xpub flag when using remote signer or preparing bundle")
+		}
+
+		input.Key = ctx.String("xpub")
+		input.KeyType = pkg.KeyPublic
+	} else if len(ctx.String("xpub")) > 0 {
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide remote signer using --signer flag (or --prepare flag) when using extended public key")
+	}
+
+	if prepare && len(ctx.String("dry-run")) > 0 {
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please use either --prepare or --dry-run flag")
+	}
+
+	if input.IsEmpty() {
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide account extended private key using --xprv, --mnemonic or --key-name flag")
 	}
 
 	from := ctx.Uint("from")
 	until := ctx.Uint("until")
-	if until == 0 {
-		return "", "", 0, 0, "", nil, errors.New("Please provide account scan limit with --until flag")
+	gap := ctx.Uint("gap")
+	if until == 0 && gap == 0 {
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide account scan limit with --until or --gap flag")
 	}
 
-	if from > until {
-		return "", "", 0, 0, "", nil, errors.New("From should be greater than until")
+	if until > 0 && from > until {
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("From should be greater than until")
 	}
 
 	dest := ctx.String("destination")
 	if len(dest) == 0 {
-		return "", "", 0, 0, "", nil, errors.New("Please provide destination address using --destination flag")
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide destination address using --destination flag")
 	}
 
 	if !common.IsHexAddress(dest) {
-		return "", "", 0, 0, "", nil, errors.New("Please provide valid destination address using --destination flag")
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide valid destination address using --destination flag")
 	}
 
 	raw := ctx.String("amount")
 	if len(raw) == 0 {
-		return "", "", 0, 0, "", nil, errors.New("Please provide amount using --amount flag")
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Please provide amount using --amount flag")
 	}
 
 	amount, err := pkg.AmountToWei(raw)
 	if err != nil {
-		return "", "", 0, 0, "", nil, err
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, err
 	}
 
 	if amount.Cmp(pkg.BigZero) <= 0 { // amount <= 0
-		return "", "", 0, 0, "", nil, errors.New("Amount should be greater than zero")
+		return "", pkg.KeychainInput{}, "", 0, 0, 0, "", nil, errors.New("Amount should be greater than zero")
+	}
+
+	return rpc, input, url, from, until, gap, dest, amount, nil
+}
+
+func keychainInput(ctx *cli.Context) pkg.KeychainInput {
+	return pkg.KeychainInput{
+		Key:            ctx.String("xprv"),
+		KeyType:        pkg.KeyPrivate,
+		Insecure:       ctx.Bool("insecure"),
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
+		KeyName:        ctx.String("key-name"),
+		VaultPath:      ctx.String("vault"),
+		VaultPassword:  ctx.String("vault-password"),
+	}
+}
+
+func setWait(ctx *cli.Context, manager *pkg.Manager) error {
+	if !ctx.Bool("wait") {
+		return nil
+	}
+
+	if ctx.Uint64("confirmations") == 0 {
+		return errors.New("Please provide at least one confirmation using --confirmations flag")
+	}
+
+	manager.Confirmations = ctx.Uint64("confirmations")
+	manager.WaitTimeout = ctx.Duration("timeout")
+	return nil
+}
+
+func setDynamicFee(ctx *cli.Context, manager *pkg.Manager) error {
+	tip := ctx.String("tip")
+	feeCap := ctx.String("fee-cap")
+	if !ctx.Bool("eip1559") && len(tip) == 0 && len(feeCap) == 0 {
+		return nil
+	}
+
+	if ctx.Uint64("fee") > 0 {
+		return errors.New("Please use either --fee flag or --tip and --fee-cap flags")
+	}
+
+	var err error
+	manager.DynamicFee = true
+	if len(tip) > 0 {
+		manager.GasTipCap, err = pkg.GweiToWei(tip)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(feeCap) > 0 {
+		manager.GasPrice, err = pkg.GweiToWei(feeCap)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setGasStrategy(ctx *cli.Context, manager *pkg.Manager) error {
+	if ctx.IsSet("gas-strategy") && (ctx.Uint64("fee") > 0 || manager.DynamicFee) {
+		return errors.New("Please use --gas-strategy flag without --fee flag and dynamic fee flags")
+	}
+
+	var err error
+	manager.GasStrategy, err = pkg.ParseGasStrategy(ctx.String("gas-strategy"))
+	if err != nil {
+		return err
+	}
+
+	if maxFee := ctx.String("max-fee"); len(maxFee) > 0 {
+		manager.MaxGasPrice, err = pkg.GweiToWei(maxFee)
+		if err != nil {
+			return err
+		}
+	}
+
+	if maxTotalFees := ctx.String("max-total-fees"); len(maxTotalFees) > 0 {
+		manager.MaxTotalFees, err = pkg.AmountToWei(maxTotalFees)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resume(ctx *cli.Context, path string) error {
+	rpc := ctx.String("rpc")
+	if len(rpc) == 0 {
+		return errors.New("Please provide RPC URL using --rpc flag")
+	}
+
+	// Read journal
+	journal, err := pkg.OpenJournal(path)
+	if err != nil {
+		return err
+	}
+
+	if journal.Bundle.Kind != pkg.BundleCollect {
+		return fmt.Errorf("Journal %s is not %s journal", path, pkg.BundleCollect)
+	}
+
+	journal.Print()
+
+	// Init manager
+	wei := ctx.Bool("wei")
+	manager, err := pkg.NewManager(rpc, journal.Bundle.ChainID.Uint64(), 0, wei)
+	if err != nil {
+		return err
+	}
+
+	if err := manager.CheckChainID(); err != nil {
+		return err
+	}
+
+	if err := setWait(ctx, manager); err != nil {
+		return err
+	}
+
+	// Key is only needed if interrupted before signing
+	var signer pkg.Signer
+	if !journal.Bundle.Signed() {
+		input := keychainInput(ctx)
+		input.Path = journal.Bundle.Path
+		if url := pkg.SignerURL(ctx.String("signer"), !input.IsEmpty()); len(url) > 0 {
+			signer, err = pkg.NewRemoteSigner(url)
+			if err != nil {
+				return err
+			}
+		} else {
+			if input.IsEmpty() {
+				return errors.New("Journal transactions are not signed, please provide account extended private key using --xprv, --mnemonic or --key-name flag")
+			}
+
+			keychain, err := input.Keychain()
+			if err != nil {
+				return err
+			}
+
+			signer = pkg.NewHDSigner(keychain, 0, 0)
+		}
 	}
 
-	return rpc, xprv, from, until, dest, amount, nil
+	total, value, err := manager.Resume(journal, signer)
+	units := pkg.Units(wei)
+	sent := pkg.WeiOrEther(value, wei)
+	fmt.Printf("Sent %d transactions, total %s %s\n", total, sent.String(), units)
+	return err
 }
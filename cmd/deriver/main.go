@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "deriver"
+	app.Usage = "lists derived addresses offline"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "xpub",
+			Usage: "account extended public key",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
+		},
+		cli.UintFlag{
+			Name:  "from",
+			Usage: "start account number",
+			Value: 0,
+		},
+		cli.UintFlag{
+			Name:  "until",
+			Usage: "final account number",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "output format (" + strings.Join(pkg.Formats, ", ") + ")",
+			Value: "table",
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "output file (defaults to stdout)",
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Parse CLI flags
+		input, from, until, format, err := parseFlags(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Init keychain
+		keychain, err := input.Keychain()
+		if err != nil {
+			return err
+		}
+
+		// Prepare accounts
+		accounts := []uint32{}
+		for i := from; i <= until; i++ {
+			accounts = append(accounts, uint32(i))
+		}
+
+		// Derive keys
+		keys, err := keychain.DeriveMultiPublic(accounts)
+		if err != nil {
+			return err
+		}
+
+		list := []pkg.AddressInfo{}
+		for i, key := range keys {
+			list = append(list, pkg.NewAddressInfo(keychain, accounts[i], key))
+		}
+
+		// Write output
+		out := os.Stdout
+		if path := ctx.String("output"); len(path) > 0 {
+			out, err = os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+
+		return pkg.WriteAddresses(out, format, list)
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func parseFlags(ctx *cli.Context) (pkg.KeychainInput, uint, uint, string, error) {
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xpub"),
+		KeyType:        pkg.KeyPublic,
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
+	}
+
+	if input.IsEmpty() {
+		return pkg.KeychainInput{}, 0, 0, "", errors.New("Please provide account extended public key using --xpub or --mnemonic flag")
+	}
+
+	from := ctx.Uint("from")
+	until := ctx.Uint("until")
+	if until == 0 {
+		return pkg.KeychainInput{}, 0, 0, "", errors.New("Please provide final account number with --until flag")
+	}
+
+	if from > until {
+		return pkg.KeychainInput{}, 0, 0, "", errors.New("From should be greater than until")
+	}
+
+	format := ctx.String("format")
+	if !pkg.IsValidFormat(format) {
+		return pkg.KeychainInput{}, 0, 0, "", errors.New("Please provide valid output format with --format flag")
+	}
+
+	return input, from, until, format, nil
+}
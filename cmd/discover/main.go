@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "discover"
+	app.Usage = "finds used addresses across common Ethereum derivation schemes"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "wei",
+			Usage: "output values in wei",
+		},
+		cli.StringFlag{
+			Name:  "rpc",
+			Usage: "Ethereum node RPC URL",
+		},
+		cli.Uint64Flag{
+			Name:  "chain",
+			Usage: "Ethereum chain ID",
+			Value: 1,
+		},
+		cli.StringFlag{
+			Name:  "xprv",
+			Usage: "master extended private key (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringSliceFlag{
+			Name:  "path",
+			Usage: "custom derivation path template to scan instead of known schemes (may be repeated)",
+		},
+		cli.UintFlag{
+			Name:  "gap",
+			Usage: "number of consecutive unused addresses to stop scanning a scheme",
+			Value: 20,
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Parse CLI flags
+		rpc, input, schemes, gap, err := parseFlags(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Init master key
+		master, err := input.ExtendedKey()
+		if err != nil {
+			return err
+		}
+
+		if master.Depth() != 0 {
+			return errors.New("Please provide master key (depth 0) or mnemonic, account level keys can't be used for discovery")
+		}
+
+		// Init manager
+		chain := ctx.Uint64("chain")
+		wei := ctx.Bool("wei")
+		manager, err := pkg.NewManager(rpc, chain, 0, wei)
+		if err != nil {
+			return err
+		}
+
+		// Scan schemes
+		units := pkg.Units(wei)
+		used := []pkg.Scheme{}
+		for _, scheme := range schemes {
+			fmt.Printf("Scanning %s (%s)...\n", scheme.Name, scheme.Path)
+			path, err := pkg.ParsePath(scheme.Path, 0, pkg.ExternalChain)
+			if err != nil {
+				return err
+			}
+
+			keychain, err := pkg.NewFromKey(master, path)
+			if err != nil {
+				return err
+			}
+
+			result, err := manager.ScanUsed(keychain, 0, gap)
+			if err != nil {
+				return err
+			}
+
+			if len(result.Data) == 0 {
+				fmt.Printf("No used addresses found\n\n")
+				continue
+			}
+
+			for _, data := range result.Data {
+				balance := pkg.WeiOrEther(data.Balance, wei)
+				fmt.Printf("- %s (%s) has %s %s and nonce %d\n", keychain.DerivationPath(data.ID), data.Address.String(), balance.String(), units, data.Nonce)
+			}
+
+			fmt.Printf("Highest used index: %d\n\n", result.LastUsed)
+			used = append(used, scheme)
+		}
+
+		if len(used) == 0 {
+			return errors.New("No used addresses found (for selected schemes)")
+		}
+
+		fmt.Printf("Used schemes:\n")
+		for _, scheme := range used {
+			fmt.Printf("- %s (%s)\n", scheme.Name, scheme.Path)
+		}
+
+		return nil
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func parseFlags(ctx *cli.Context) (string, pkg.KeychainInput, []pkg.Scheme, uint, error) {
+	rpc := ctx.String("rpc")
+	if len(rpc) == 0 {
+		return "", pkg.KeychainInput{}, nil, 0, errors.New("Please provide RPC URL using --rpc flag")
+	}
+
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xprv"),
+		KeyType:        pkg.KeyPrivate,
+		Insecure:       ctx.Bool("insecure"),
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+	}
+
+	if input.IsEmpty() {
+		return "", pkg.KeychainInput{}, nil, 0, errors.New("Please provide master extended private key using --xprv or --mnemonic flag")
+	}
+
+	schemes := pkg.Schemes
+	if paths := ctx.StringSlice("path"); len(paths) > 0 {
+		schemes = []pkg.Scheme{}
+		for _, path := range paths {
+			schemes = append(schemes, pkg.Scheme{Name: "Custom", Path: path})
+		}
+	}
+
+	gap := ctx.Uint("gap")
+	if gap == 0 {
+		return "", pkg.KeychainInput{}, nil, 0, errors.New("Please provide valid gap limit with --gap flag")
+	}
+
+	return rpc, input, schemes, gap, nil
+}
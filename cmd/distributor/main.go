@@ -1,11 +1,14 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
 	"os"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/pavel-main/ethereum-hd-tools/pkg"
 	"github.com/urfave/cli"
 )
@@ -40,13 +43,91 @@ func main() {
 			Usage: "custom gas price (in gwei)",
 			Value: 0,
 		},
+		cli.StringFlag{
+			Name:  "gas-strategy",
+			Usage: "gas price strategy without --fee: node, percentile:PERCENT[:BLOCKS], fixed:GWEI or multiplier:FACTOR",
+			Value: "node",
+		},
+		cli.StringFlag{
+			Name:  "max-fee",
+			Usage: "abort if network gas price (base fee plus priority fee of dynamic fee transactions) exceeds it (in gwei)",
+		},
+		cli.StringFlag{
+			Name:  "max-total-fees",
+			Usage: "abort if maximum fees of all transactions exceed it (in ETH)",
+		},
+		cli.Float64Flag{
+			Name:  "gas-multiplier",
+			Usage: "safety multiplier of estimated gas limits above 21000 (transfers to contracts)",
+			Value: pkg.DefaultGasMultiplier,
+		},
+		cli.BoolFlag{
+			Name:  "eip1559",
+			Usage: "send dynamic fee (EIP-1559) transactions with fees suggested from recent blocks",
+		},
+		cli.StringFlag{
+			Name:  "tip",
+			Usage: "max priority fee per gas of dynamic fee transactions (in gwei), implies --eip1559",
+		},
+		cli.StringFlag{
+			Name:  "fee-cap",
+			Usage: "max fee per gas of dynamic fee transactions (in gwei), implies --eip1559",
+		},
 		cli.StringFlag{
 			Name:  "xpub",
 			Usage: "destination account extended public key",
 		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
+		},
 		cli.StringFlag{
 			Name:  "prv",
-			Usage: "source account private key",
+			Usage: "source account private key (-, prompt, env:NAME, file:PATH or keystore:PATH)",
+		},
+		cli.StringFlag{
+			Name:  "key-name",
+			Usage: "name of source account private key in vault",
+		},
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "vault-password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+		cli.StringFlag{
+			Name:  "signer",
+			Usage: "remote Clef-style signer URL or unix socket path, instead of private key (defaults to $HD_AGENT_SOCK)",
+		},
+		cli.StringFlag{
+			Name:  "sender",
+			Usage: "source account address (with --prepare, or at remote signer holding multiple accounts)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
 		},
 		cli.UintFlag{
 			Name:  "from",
@@ -67,11 +148,49 @@ func main() {
 			Name:  "amount",
 			Usage: "amount to transfer to each account (in ETH)",
 		},
+		cli.StringFlag{
+			Name:  "prepare",
+			Usage: "write unsigned transaction bundle to file for offline signing instead of sending",
+		},
+		cli.StringFlag{
+			Name:  "dry-run",
+			Usage: "sign transactions and write them to file instead of sending (send later with broadcast command)",
+		},
+		cli.StringFlag{
+			Name:  "journal",
+			Usage: "journal directory (default: ~/.ethereum-hd-tools/journal)",
+		},
+		cli.StringFlag{
+			Name:  "resume",
+			Usage: "resume interrupted operation from journal file, sending only outstanding transactions",
+		},
+		cli.BoolFlag{
+			Name:  "allow-pending",
+			Usage: "proceed even if addresses have pending transactions not sent by this tool",
+		},
+		cli.BoolFlag{
+			Name:  "wait",
+			Usage: "wait for transaction receipts and print report",
+		},
+		cli.Uint64Flag{
+			Name:  "confirmations",
+			Usage: "number of confirmations to wait for (with --wait)",
+			Value: 1,
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "maximum time to wait for receipts (with --wait)",
+			Value: 10 * time.Minute,
+		},
 	}
 
 	app.Action = func(ctx *cli.Context) error {
+		if path := ctx.String("resume"); len(path) > 0 {
+			return resume(ctx, path)
+		}
+
 		// Parse CLI flags
-		rpc, prv, xpub, from, until, step, amount, err := parseFlags(ctx)
+		rpc, prv, input, from, until, step, amount, err := parseFlags(ctx)
 		if err != nil {
 			return err
 		}
@@ -82,6 +201,27 @@ func main() {
 			accounts = append(accounts, uint32(i))
 		}
 
+		// Init keychain
+		keychain, err := input.Keychain()
+		if err != nil {
+			return err
+		}
+
+		// Init signer (preparing bundle for offline signing only needs source address)
+		var signer pkg.Signer
+		account := pkg.Account{Address: common.HexToAddress(ctx.String("sender"))}
+		if len(ctx.String("prepare")) == 0 {
+			signer, err = newSigner(ctx, prv)
+			if err != nil {
+				return err
+			}
+
+			account, err = pkg.SelectAccount(signer, ctx.String("sender"))
+			if err != nil {
+				return err
+			}
+		}
+
 		// Init manager
 		chain := ctx.Uint64("chain")
 		fee := ctx.Uint64("fee")
@@ -91,32 +231,64 @@ func main() {
 			return err
 		}
 
-		// Set gas price
-		if err := manager.SetGasPrice(); err != nil {
+		manager.DryRun = ctx.String("dry-run")
+		manager.Nonces.AllowPending = ctx.Bool("allow-pending")
+		manager.JournalDir = ctx.String("journal")
+		if len(manager.JournalDir) == 0 {
+			manager.JournalDir = pkg.DefaultJournalDir()
+		}
+
+		manager.GasMultiplier = ctx.Float64("gas-multiplier")
+		if manager.GasMultiplier < 1 {
+			return errors.New("Gas multiplier should not be less than 1")
+		}
+
+		if err := setWait(ctx, manager); err != nil {
 			return err
 		}
 
-		// Init keychain
-		keychain, err := pkg.New(xpub)
-		if err != nil {
+		if err := setDynamicFee(ctx, manager); err != nil {
 			return err
 		}
 
-		// Derive keys
-		keys, err := keychain.DeriveMultiPublic(accounts)
-		if err != nil {
+		if err := setGasStrategy(ctx, manager); err != nil {
+			return err
+		}
+
+		// Set gas price
+		if err := manager.SetGasPrice(); err != nil {
 			return err
 		}
 
-		// Parse private key
-		key, err := manager.GetPrivateKey(prv)
+		// Derive keys
+		recipients, err := keychain.DeriveMultiAddress(accounts)
 		if err != nil {
 			return err
 		}
 
-		// Distribute
+		// Write unsigned bundle for offline signing
 		random := ctx.Bool("random")
-		total, err := manager.Distribute(key, keys, amount, random)
+		if prepare := ctx.String("prepare"); len(prepare) > 0 {
+			bundle, err := manager.PlanDistribute(keychain, account.Address, recipients, amount, random)
+			if err != nil {
+				return err
+			}
+
+			bundle.Print(wei)
+			if err := bundle.WriteFile(prepare); err != nil {
+				return err
+			}
+
+			fmt.Printf("Unsigned bundle written to %s\n", prepare)
+			return nil
+		}
+
+		// Distribute
+		total, err := manager.Distribute(signer, account, keychain, recipients, amount, random)
+		if len(manager.DryRun) > 0 {
+			return err
+		}
+
 		fmt.Printf("Sent %d transactions of %d\n", total, len(accounts))
 		return err
 	}
@@ -127,50 +299,235 @@ func main() {
 	}
 }
 
-func parseFlags(ctx *cli.Context) (string, string, string, uint, uint, uint, *big.Int, error) {
+func parseFlags(ctx *cli.Context) (string, string, pkg.KeychainInput, uint, uint, uint, *big.Int, error) {
 	rpc := ctx.String("rpc")
 	if len(rpc) == 0 {
-		return "", "", "", 0, 0, 0, nil, errors.New("Please provide RPC URL using --rpc flag")
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please provide RPC URL using --rpc flag")
 	}
 
 	prv := ctx.String("prv")
-	if len(prv) == 0 {
-		return "", "", "", 0, 0, 0, nil, errors.New("Please provide private key using --prv flag")
+	sources := 0
+	for _, name := range []string{"prv", "key-name", "signer"} {
+		if len(ctx.String(name)) > 0 {
+			sources++
+		}
 	}
 
-	xpub := ctx.String("xpub")
-	if len(xpub) == 0 {
-		return "", "", "", 0, 0, 0, nil, errors.New("Please provie account extended public key using --xpub flag")
+	if len(ctx.String("prepare")) > 0 {
+		if len(ctx.String("dry-run")) > 0 {
+			return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please use either --prepare or --dry-run flag")
+		}
+
+		if sources > 0 {
+			return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Private key is not needed when preparing bundle, please provide source address using --sender flag")
+		}
+
+		if !common.IsHexAddress(ctx.String("sender")) {
+			return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please provide valid source address using --sender flag")
+		}
+	} else if sources == 0 && len(pkg.SignerURL("", false)) == 0 {
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please provide private key using --prv, --key-name or --signer flag")
+	}
+
+	if sources > 1 {
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Private key, vault key name and remote signer are mutually exclusive")
+	}
+
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xpub"),
+		KeyType:        pkg.KeyPublic,
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
+	}
+
+	if input.IsEmpty() {
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please provide account extended public key using --xpub or --mnemonic flag")
 	}
 
 	from := ctx.Uint("from")
 	until := ctx.Uint("until")
 	step := ctx.Uint("step")
 	if until == 0 {
-		return "", "", "", 0, 0, 0, nil, errors.New("Please provide account scan limit with --until flag")
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please provide account scan limit with --until flag")
 	}
 
 	if step == 0 {
-		return "", "", "", 0, 0, 0, nil, errors.New("Please provide valid step size with --step flag")
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please provide valid step size with --step flag")
 	}
 
 	if from > until {
-		return "", "", "", 0, 0, 0, nil, errors.New("From should be greater than until")
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("From should be greater than until")
 	}
 
 	raw := ctx.String("amount")
 	if len(raw) == 0 {
-		return "", "", "", 0, 0, 0, nil, errors.New("Please provide amount using --amount flag")
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Please provide amount using --amount flag")
 	}
 
 	amount, err := pkg.AmountToWei(raw)
 	if err != nil {
-		return "", "", "", 0, 0, 0, nil, err
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, err
 	}
 
 	if amount.Cmp(pkg.BigZero) <= 0 { // amount <= 0
-		return "", "", "", 0, 0, 0, nil, errors.New("Amount should be greater than zero")
+		return "", "", pkg.KeychainInput{}, 0, 0, 0, nil, errors.New("Amount should be greater than zero")
+	}
+
+	return rpc, prv, input, from, until, step, amount, nil
+}
+
+func newSigner(ctx *cli.Context, prv string) (pkg.Signer, error) {
+	local := len(prv) > 0 || len(ctx.String("key-name")) > 0
+	if url := pkg.SignerURL(ctx.String("signer"), local); len(url) > 0 {
+		return pkg.NewRemoteSigner(url)
+	}
+
+	var key *ecdsa.PrivateKey
+	if name := ctx.String("key-name"); len(name) > 0 {
+		entry, err := pkg.UnlockVaultEntry(ctx.String("vault"), ctx.String("vault-password"), name)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err = entry.PrivateKey()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err := pkg.ReadKey(prv, "Enter private key: ", ctx.Bool("insecure"))
+		if err != nil {
+			return nil, err
+		}
+
+		key, err = pkg.GetPrivateKey(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pkg.NewKeySigner(key), nil
+}
+
+func setWait(ctx *cli.Context, manager *pkg.Manager) error {
+	if !ctx.Bool("wait") {
+		return nil
+	}
+
+	if ctx.Uint64("confirmations") == 0 {
+		return errors.New("Please provide at least one confirmation using --confirmations flag")
+	}
+
+	manager.Confirmations = ctx.Uint64("confirmations")
+	manager.WaitTimeout = ctx.Duration("timeout")
+	return nil
+}
+
+func setDynamicFee(ctx *cli.Context, manager *pkg.Manager) error {
+	tip := ctx.String("tip")
+	feeCap := ctx.String("fee-cap")
+	if !ctx.Bool("eip1559") && len(tip) == 0 && len(feeCap) == 0 {
+		return nil
+	}
+
+	if ctx.Uint64("fee") > 0 {
+		return errors.New("Please use either --fee flag or --tip and --fee-cap flags")
+	}
+
+	var err error
+	manager.DynamicFee = true
+	if len(tip) > 0 {
+		manager.GasTipCap, err = pkg.GweiToWei(tip)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(feeCap) > 0 {
+		manager.GasPrice, err = pkg.GweiToWei(feeCap)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setGasStrategy(ctx *cli.Context, manager *pkg.Manager) error {
+	if ctx.IsSet("gas-strategy") && (ctx.Uint64("fee") > 0 || manager.DynamicFee) {
+		return errors.New("Please use --gas-strategy flag without --fee flag and dynamic fee flags")
+	}
+
+	var err error
+	manager.GasStrategy, err = pkg.ParseGasStrategy(ctx.String("gas-strategy"))
+	if err != nil {
+		return err
+	}
+
+	if maxFee := ctx.String("max-fee"); len(maxFee) > 0 {
+		manager.MaxGasPrice, err = pkg.GweiToWei(maxFee)
+		if err != nil {
+			return err
+		}
+	}
+
+	if maxTotalFees := ctx.String("max-total-fees"); len(maxTotalFees) > 0 {
+		manager.MaxTotalFees, err = pkg.AmountToWei(maxTotalFees)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resume(ctx *cli.Context, path string) error {
+	rpc := ctx.String("rpc")
+	if len(rpc) == 0 {
+		return errors.New("Please provide RPC URL using --rpc flag")
+	}
+
+	// Read journal
+	journal, err := pkg.OpenJournal(path)
+	if err != nil {
+		return err
+	}
+
+	if journal.Bundle.Kind != pkg.BundleDistribute {
+		return fmt.Errorf("Journal %s is not %s journal", path, pkg.BundleDistribute)
+	}
+
+	journal.Print()
+
+	// Init manager
+	wei := ctx.Bool("wei")
+	manager, err := pkg.NewManager(rpc, journal.Bundle.ChainID.Uint64(), 0, wei)
+	if err != nil {
+		return err
+	}
+
+	if err := manager.CheckChainID(); err != nil {
+		return err
+	}
+
+	if err := setWait(ctx, manager); err != nil {
+		return err
+	}
+
+	// Key is only needed if interrupted before signing
+	var signer pkg.Signer
+	if !journal.Bundle.Signed() {
+		signer, err = newSigner(ctx, ctx.String("prv"))
+		if err != nil {
+			return err
+		}
 	}
 
-	return rpc, prv, xpub, from, until, step, amount, nil
+	total, value, err := manager.Resume(journal, signer)
+	units := pkg.Units(wei)
+	sent := pkg.WeiOrEther(value, wei)
+	fmt.Printf("Sent %d transactions, total %s %s\n", total, sent.String(), units)
+	return err
 }
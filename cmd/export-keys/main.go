@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "export-keys"
+	app.Usage = "exports derived private keys as V3 keystore files"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "xprv",
+			Usage: "account extended private key (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "key-name",
+			Usage: "name of extended private key or mnemonic in vault",
+		},
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "vault-password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
+		},
+		cli.UintFlag{
+			Name:  "from",
+			Usage: "start account number",
+			Value: 0,
+		},
+		cli.UintFlag{
+			Name:  "until",
+			Usage: "final account number (defaults to start account number)",
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "keystore directory",
+			Value: "keystore",
+		},
+		cli.StringFlag{
+			Name:  "password",
+			Usage: "shared keystore password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+		cli.StringFlag{
+			Name:  "passwords",
+			Usage: "file with one keystore password per line (per-file passwords, in account order)",
+		},
+		cli.BoolFlag{
+			Name:  "light",
+			Usage: "use light scrypt parameters (faster, less secure)",
+		},
+		cli.BoolFlag{
+			Name:  "dangerously-print-raw-keys",
+			Usage: "print unencrypted private keys (hex) to stdout instead of writing keystores",
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Parse CLI flags
+		input, from, until, err := parseFlags(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Prepare accounts
+		accounts := []uint32{}
+		for i := from; i <= until; i++ {
+			accounts = append(accounts, uint32(i))
+		}
+
+		// Init keychain
+		keychain, err := input.Keychain()
+		if err != nil {
+			return err
+		}
+
+		// Raw output
+		if ctx.Bool("dangerously-print-raw-keys") {
+			keys, err := keychain.DeriveMultiPrivate(accounts)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(os.Stderr, "WARNING: printing unencrypted private keys, anyone with access to this output controls the funds")
+			for i, key := range keys {
+				address := crypto.PubkeyToAddress(key.PublicKey)
+				fmt.Printf("%d\t%s\t%s\t%064x\n", accounts[i], keychain.DerivationPath(accounts[i]), address.Hex(), key.D)
+			}
+
+			return nil
+		}
+
+		// Read passwords
+		passwords, err := readPasswords(ctx, len(accounts))
+		if err != nil {
+			return err
+		}
+
+		scryptN, scryptP := pkg.KeystoreScryptN, pkg.KeystoreScryptP
+		if ctx.Bool("light") {
+			scryptN, scryptP = pkg.KeystoreLightScryptN, pkg.KeystoreLightScryptP
+		}
+
+		dir := ctx.String("output")
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+
+		// Write keystores
+		for i, index := range accounts {
+			key, err := keychain.DerivePrivate(index)
+			if err != nil {
+				return err
+			}
+
+			data, err := pkg.EncryptKeystore(key.ToECDSA(), passwords[i], scryptN, scryptP)
+			if err != nil {
+				return err
+			}
+
+			address := crypto.PubkeyToAddress(key.PublicKey)
+			file := filepath.Join(dir, pkg.KeystoreFileName(address, time.Now()))
+			if err := ioutil.WriteFile(file, data, 0600); err != nil {
+				return err
+			}
+
+			fmt.Printf("Exported account %d (%s) to %s\n", index, address.Hex(), file)
+		}
+
+		return nil
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func parseFlags(ctx *cli.Context) (pkg.KeychainInput, uint, uint, error) {
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xprv"),
+		KeyType:        pkg.KeyPrivate,
+		Insecure:       ctx.Bool("insecure"),
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
+		KeyName:        ctx.String("key-name"),
+		VaultPath:      ctx.String("vault"),
+		VaultPassword:  ctx.String("vault-password"),
+	}
+
+	if input.IsEmpty() {
+		return pkg.KeychainInput{}, 0, 0, errors.New("Please provide account extended private key using --xprv, --mnemonic or --key-name flag")
+	}
+
+	from := ctx.Uint("from")
+	until := ctx.Uint("until")
+	if until == 0 {
+		until = from
+	}
+
+	if from > until {
+		return pkg.KeychainInput{}, 0, 0, errors.New("From should be greater than until")
+	}
+
+	if ctx.Bool("dangerously-print-raw-keys") && len(ctx.String("passwords")) > 0 {
+		return pkg.KeychainInput{}, 0, 0, errors.New("Raw key output doesn't use keystore passwords")
+	}
+
+	return input, from, until, nil
+}
+
+// Returns keystore password for each account
+func readPasswords(ctx *cli.Context, count int) ([]string, error) {
+	passwords := []string{}
+	if path := ctx.String("passwords"); len(path) > 0 {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			passwords = append(passwords, strings.TrimRight(scanner.Text(), "\r"))
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+
+		if len(passwords) != count {
+			return nil, fmt.Errorf("Passwords file contains %d passwords, but %d accounts are exported", len(passwords), count)
+		}
+	} else {
+		password, err := pkg.ReadSecret(ctx.String("password"), "Enter keystore password: ")
+		if err != nil {
+			return nil, err
+		}
+
+		// Ask twice when typed interactively
+		if ctx.String("password") == "prompt" {
+			confirm, err := pkg.ReadSecret("prompt", "Repeat keystore password: ")
+			if err != nil {
+				return nil, err
+			}
+
+			if confirm != password {
+				return nil, errors.New("Passwords do not match")
+			}
+		}
+
+		for i := 0; i < count; i++ {
+			passwords = append(passwords, password)
+		}
+	}
+
+	for _, password := range passwords {
+		if len(password) == 0 {
+			return nil, errors.New("Keystore password should not be empty")
+		}
+	}
+
+	return passwords, nil
+}
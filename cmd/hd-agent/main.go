@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "hd-agent"
+	app.Usage = "holds unlocked keychain in memory and signs transactions over unix socket"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.Uint64Flag{
+			Name:  "chain",
+			Usage: "Ethereum chain ID",
+			Value: 1,
+		},
+		cli.StringFlag{
+			Name:  "socket",
+			Usage: "unix socket path (default: agent.sock in new private temp directory)",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "exit after no requests for this long (0 to never exit)",
+			Value: 15 * time.Minute,
+		},
+		cli.StringFlag{
+			Name:  "xprv",
+			Usage: "account extended private key (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "key-name",
+			Usage: "name of extended private key or mnemonic in vault",
+		},
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "vault-password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
+		},
+		cli.StringSliceFlag{
+			Name:  "allow-range",
+			Usage: "allowed account numbers, e.g. 0-99 (default: 0-999)",
+		},
+		cli.StringFlag{
+			Name:  "max-value",
+			Usage: "maximum value per transaction (in ETH)",
+		},
+		cli.StringFlag{
+			Name:  "max-tx-fee",
+			Usage: "maximum fee per transaction, gas limit times (max) gas price (in ETH)",
+		},
+		cli.StringSliceFlag{
+			Name:  "allow-to",
+			Usage: "allowed destination address (any if omitted)",
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Parse CLI flags
+		input, policy, socket, err := parseFlags(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Init keychain
+		keychain, err := input.Keychain()
+		if err != nil {
+			return err
+		}
+
+		// Init signer for allowed accounts only
+		chainID := new(big.Int).SetUint64(ctx.Uint64("chain"))
+		api, err := pkg.NewSignerAPI(pkg.NewRangesHDSigner(keychain, policy.Ranges), chainID)
+		if err != nil {
+			return err
+		}
+
+		api.Policy = policy
+		api.IdleTimeout = ctx.Duration("timeout")
+
+		// Keep unlocked keys out of swap
+		if err := pkg.LockMemory(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not lock memory: %s\n", err.Error())
+		}
+
+		// Default socket is created in new private directory, as temp directory is shared
+		cleanup := func() { os.Remove(socket) }
+		if len(socket) == 0 {
+			dir, err := ioutil.TempDir("", "hd-agent-")
+			if err != nil {
+				return err
+			}
+
+			socket = filepath.Join(dir, "agent.sock")
+			cleanup = func() { os.RemoveAll(dir) }
+			defer cleanup()
+		}
+
+		// Remove socket on interrupt
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupt
+			cleanup()
+			os.Exit(0)
+		}()
+
+		fmt.Printf("%s=%s; export %s;\n", pkg.AgentSocketEnv, socket, pkg.AgentSocketEnv)
+		return api.Serve("", socket)
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func parseFlags(ctx *cli.Context) (pkg.KeychainInput, *pkg.Policy, string, error) {
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xprv"),
+		KeyType:        pkg.KeyPrivate,
+		Insecure:       ctx.Bool("insecure"),
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
+		KeyName:        ctx.String("key-name"),
+		VaultPath:      ctx.String("vault"),
+		VaultPassword:  ctx.String("vault-password"),
+	}
+
+	if input.IsEmpty() {
+		return pkg.KeychainInput{}, nil, "", errors.New("Please provide account extended private key using --xprv, --mnemonic or --key-name flag")
+	}
+
+	policy := &pkg.Policy{}
+	ranges := ctx.StringSlice("allow-range")
+	if len(ranges) == 0 {
+		ranges = []string{"0-999"}
+	}
+
+	for _, raw := range ranges {
+		r, err := pkg.ParseIndexRange(raw)
+		if err != nil {
+			return pkg.KeychainInput{}, nil, "", err
+		}
+
+		policy.Ranges = append(policy.Ranges, r)
+	}
+
+	if raw := ctx.String("max-value"); len(raw) > 0 {
+		value, err := pkg.AmountToWei(raw)
+		if err != nil {
+			return pkg.KeychainInput{}, nil, "", err
+		}
+
+		policy.MaxValue = value
+	}
+
+	if raw := ctx.String("max-tx-fee"); len(raw) > 0 {
+		fee, err := pkg.AmountToWei(raw)
+		if err != nil {
+			return pkg.KeychainInput{}, nil, "", err
+		}
+
+		policy.MaxFee = fee
+	}
+
+	for _, raw := range ctx.StringSlice("allow-to") {
+		if !common.IsHexAddress(raw) {
+			return pkg.KeychainInput{}, nil, "", fmt.Errorf("Invalid destination address %s", raw)
+		}
+
+		policy.Destinations = append(policy.Destinations, common.HexToAddress(raw))
+	}
+
+	return input, policy, ctx.String("socket"), nil
+}
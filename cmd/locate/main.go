@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "locate"
+	app.Usage = "finds derivation index of given addresses"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "xpub",
+			Usage: "account extended public key",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "both-chains",
+			Usage: "search both external and internal (change) chains",
+		},
+		cli.StringSliceFlag{
+			Name:  "address",
+			Usage: "address to locate (may be repeated)",
+		},
+		cli.StringFlag{
+			Name:  "addresses",
+			Usage: "file with addresses to locate (one per line)",
+		},
+		cli.UintFlag{
+			Name:  "from",
+			Usage: "start account number",
+			Value: 0,
+		},
+		cli.UintFlag{
+			Name:  "until",
+			Usage: "final account number",
+			Value: 1000000,
+		},
+		cli.IntFlag{
+			Name:  "workers",
+			Usage: "number of parallel workers",
+			Value: runtime.NumCPU(),
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "output format (" + strings.Join(pkg.Formats, ", ") + ")",
+			Value: "table",
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Parse CLI flags
+		input, targets, from, until, format, err := parseFlags(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Init keychains (one per chain)
+		keychains := []*pkg.Keychain{}
+		keychain, err := input.Keychain()
+		if err != nil {
+			return err
+		}
+		keychains = append(keychains, keychain)
+
+		if ctx.Bool("both-chains") {
+			input.Internal = true
+			path, err := input.DerivationPath()
+			if err != nil {
+				return err
+			}
+
+			internal, err := pkg.NewFromKey(keychain.AccountKey, path)
+			if err != nil {
+				return err
+			}
+
+			// Path may not depend on {chain} at all
+			if internal.Path.String() != keychain.Path.String() {
+				keychains = append(keychains, internal)
+			}
+		}
+
+		// Search
+		workers := ctx.Int("workers")
+		found := []pkg.AddressInfo{}
+		remaining := targets
+		for _, keychain := range keychains {
+			matches, err := keychain.Locate(remaining, uint32(from), uint32(until), workers)
+			if err != nil {
+				return err
+			}
+
+			found = append(found, matches...)
+			remaining = excludeFound(remaining, matches)
+			if len(remaining) == 0 {
+				break
+			}
+		}
+
+		if len(found) == 0 {
+			return errors.New("No addresses found (for selected accounts)")
+		}
+
+		if err := pkg.WriteAddresses(os.Stdout, format, found); err != nil {
+			return err
+		}
+
+		if len(found) < len(targets) {
+			fmt.Fprintf(os.Stderr, "Found %d addresses of %d\n", len(found), len(targets))
+		}
+
+		return nil
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func excludeFound(targets []common.Address, found []pkg.AddressInfo) []common.Address {
+	result := []common.Address{}
+	for _, target := range targets {
+		matched := false
+		for _, info := range found {
+			if common.HexToAddress(info.Address) == target {
+				matched = true
+			}
+		}
+
+		if !matched {
+			result = append(result, target)
+		}
+	}
+
+	return result
+}
+
+func parseFlags(ctx *cli.Context) (pkg.KeychainInput, []common.Address, uint, uint, string, error) {
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xpub"),
+		KeyType:        pkg.KeyPublic,
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+	}
+
+	if input.IsEmpty() {
+		return pkg.KeychainInput{}, nil, 0, 0, "", errors.New("Please provide account extended public key using --xpub or --mnemonic flag")
+	}
+
+	// Collect target addresses
+	raw := ctx.StringSlice("address")
+	if path := ctx.String("addresses"); len(path) > 0 {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return pkg.KeychainInput{}, nil, 0, 0, "", err
+		}
+		raw = append(raw, strings.Fields(string(data))...)
+	}
+
+	if len(raw) == 0 {
+		return pkg.KeychainInput{}, nil, 0, 0, "", errors.New("Please provide addresses using --address or --addresses flag")
+	}
+
+	targets := []common.Address{}
+	seen := map[common.Address]bool{}
+	for _, address := range raw {
+		if !common.IsHexAddress(address) {
+			return pkg.KeychainInput{}, nil, 0, 0, "", fmt.Errorf("Invalid address: %s", address)
+		}
+
+		target := common.HexToAddress(address)
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+
+	from := ctx.Uint("from")
+	until := ctx.Uint("until")
+	if from > until {
+		return pkg.KeychainInput{}, nil, 0, 0, "", errors.New("From should be greater than until")
+	}
+
+	format := ctx.String("format")
+	if !pkg.IsValidFormat(format) {
+		return pkg.KeychainInput{}, nil, 0, 0, "", errors.New("Please provide valid output format with --format flag")
+	}
+
+	return input, targets, from, until, format, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "qr"
+	app.Usage = "transfers transaction bundles as animated QR codes"
+	app.Version = "1.0.1"
+	app.Commands = []cli.Command{
+		{
+			Name:  "encode",
+			Usage: "shows bundle as animated QR code in terminal or writes PNG frames",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "bundle",
+					Usage: "transaction bundle file (unsigned or signed)",
+				},
+				cli.StringFlag{
+					Name:  "png",
+					Usage: "write PNG frames to directory instead of terminal",
+				},
+				cli.IntFlag{
+					Name:  "scale",
+					Usage: "PNG pixels per QR module",
+					Value: 6,
+				},
+				cli.IntFlag{
+					Name:  "chunk",
+					Usage: "compressed bytes per frame",
+					Value: pkg.DefaultQRChunkSize,
+				},
+				cli.DurationFlag{
+					Name:  "interval",
+					Usage: "terminal frame interval",
+					Value: 500 * time.Millisecond,
+				},
+				cli.IntFlag{
+					Name:  "loops",
+					Usage: "number of terminal animation loops (0 to loop until interrupted)",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				path := ctx.String("bundle")
+				if len(path) == 0 {
+					return errors.New("Please provide transaction bundle using --bundle flag")
+				}
+
+				if ctx.Int("scale") < 1 {
+					return errors.New("Scale should be at least 1")
+				}
+
+				// Read & verify bundle
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				}
+
+				if _, err := pkg.ParseBundle(data); err != nil {
+					return err
+				}
+
+				frames, err := pkg.EncodeFrames(data, ctx.Int("chunk"))
+				if err != nil {
+					return err
+				}
+
+				// Write PNG frames
+				if dir := ctx.String("png"); len(dir) > 0 {
+					if err := os.MkdirAll(dir, 0755); err != nil {
+						return err
+					}
+
+					for i, frame := range frames {
+						file := filepath.Join(dir, fmt.Sprintf("frame-%04d.png", i+1))
+						if err := pkg.WriteQRPNG(file, frame, ctx.Int("scale")); err != nil {
+							return err
+						}
+					}
+
+					fmt.Printf("Wrote %d frames to %s\n", len(frames), dir)
+					return nil
+				}
+
+				// Animate in terminal
+				for loop := 0; ctx.Int("loops") == 0 || loop < ctx.Int("loops"); loop++ {
+					for i, frame := range frames {
+						fmt.Print("\033[H\033[2J")
+						if err := pkg.WriteQRTerminal(os.Stdout, frame); err != nil {
+							return err
+						}
+
+						fmt.Printf("Frame %d of %d\n", i+1, len(frames))
+						time.Sleep(ctx.Duration("interval"))
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Name:  "decode",
+			Usage: "restores bundle from directory of scanned frames (images or text files)",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "frames",
+					Usage: "directory with scanned frames (PNG, JPEG, GIF or text files with one frame per line)",
+				},
+				cli.StringFlag{
+					Name:  "output",
+					Usage: "transaction bundle file",
+				},
+				cli.BoolFlag{
+					Name:  "wei",
+					Usage: "output values in wei",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				dir := ctx.String("frames")
+				if len(dir) == 0 {
+					return errors.New("Please provide frames directory using --frames flag")
+				}
+
+				output := ctx.String("output")
+				if len(output) == 0 {
+					return errors.New("Please provide output file using --output flag")
+				}
+
+				// Read & join frames
+				frames, err := pkg.ReadFramesDir(dir)
+				if err != nil {
+					return err
+				}
+
+				data, err := pkg.DecodeFrames(frames)
+				if err != nil {
+					return err
+				}
+
+				// Verify bundle
+				bundle, err := pkg.ParseBundle(data)
+				if err != nil {
+					return err
+				}
+
+				bundle.Print(ctx.Bool("wei"))
+				if err := ioutil.WriteFile(output, data, 0644); err != nil {
+					return err
+				}
+
+				fmt.Printf("Bundle written to %s\n", output)
+				return nil
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
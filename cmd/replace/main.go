@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "replace"
+	app.Usage = "speeds up or cancels stuck transactions"
+	app.Version = "1.0.1"
+
+	flags := []cli.Flag{
+		cli.BoolFlag{
+			Name:  "wei",
+			Usage: "output values in wei",
+		},
+		cli.StringFlag{
+			Name:  "rpc",
+			Usage: "Ethereum node RPC URL",
+		},
+		cli.StringFlag{
+			Name:  "hash",
+			Usage: "hash of stuck transaction",
+		},
+		cli.StringFlag{
+			Name:  "journal",
+			Usage: "journal file of collector or distributor (replaces all sent, but not mined transactions)",
+		},
+		cli.Uint64Flag{
+			Name:  "fee",
+			Usage: "custom gas price (in gwei), used if higher than replacement threshold",
+			Value: 0,
+		},
+		cli.Uint64Flag{
+			Name:  "bump",
+			Usage: "gas price bump over original transaction (in percent)",
+			Value: pkg.DefaultPriceBump,
+		},
+		cli.StringFlag{
+			Name:  "prv",
+			Usage: "sender private key (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.StringFlag{
+			Name:  "xprv",
+			Usage: "account extended private key of derived senders (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.StringFlag{
+			Name:  "signer",
+			Usage: "remote Clef-style signer URL or unix socket path (defaults to $HD_AGENT_SOCK without local key)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "key-name",
+			Usage: "name of private key, extended private key or mnemonic in vault",
+		},
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "vault-password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
+		},
+		cli.UintFlag{
+			Name:  "from",
+			Usage: "start account number to search senders in",
+			Value: 0,
+		},
+		cli.UintFlag{
+			Name:  "until",
+			Usage: "final account number to search senders in",
+			Value: 1000,
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{
+			Name:  "speedup",
+			Usage: "resends stuck transactions with bumped gas price",
+			Flags: flags,
+			Action: func(ctx *cli.Context) error {
+				return replace(ctx, false)
+			},
+		},
+		{
+			Name:  "cancel",
+			Usage: "replaces stuck transactions with zero-value self-transfers",
+			Flags: flags,
+			Action: func(ctx *cli.Context) error {
+				return replace(ctx, true)
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func replace(ctx *cli.Context, cancel bool) error {
+	// Parse CLI flags
+	rpc := ctx.String("rpc")
+	if len(rpc) == 0 {
+		return errors.New("Please provide RPC URL using --rpc flag")
+	}
+
+	hash := ctx.String("hash")
+	path := ctx.String("journal")
+	if (len(hash) == 0) == (len(path) == 0) {
+		return errors.New("Please provide transaction hash using --hash flag or journal using --journal flag")
+	}
+
+	if len(hash) > 0 && len(common.FromHex(hash)) != common.HashLength {
+		return errors.New("Please provide valid transaction hash using --hash flag")
+	}
+
+	// Init manager
+	wei := ctx.Bool("wei")
+	manager, err := pkg.NewManager(rpc, 0, ctx.Uint64("fee"), wei)
+	if err != nil {
+		return err
+	}
+
+	manager.ChainID, err = manager.NodeChainID()
+	if err != nil {
+		return err
+	}
+
+	if err := manager.SetGasPrice(); err != nil {
+		return err
+	}
+
+	// Find stuck txs
+	var journal *pkg.Journal
+	var indexes []int
+	var stuck []*pkg.StuckTx
+	if len(path) > 0 {
+		journal, err = pkg.OpenJournal(path)
+		if err != nil {
+			return err
+		}
+
+		if journal.Bundle.ChainID.Cmp(manager.ChainID) != 0 {
+			return fmt.Errorf("Journal chain ID %s doesn't match node chain ID %s", journal.Bundle.ChainID.String(), manager.ChainID.String())
+		}
+
+		indexes, stuck, err = manager.JournalStuckTxs(journal)
+		if err != nil {
+			return err
+		}
+	} else {
+		tx, err := manager.StuckTransaction(common.HexToHash(hash))
+		if err != nil {
+			return err
+		}
+
+		stuck = append(stuck, tx)
+	}
+
+	if len(stuck) == 0 {
+		return errors.New("No stuck transactions found")
+	}
+
+	// Init signer
+	signer, keychain, err := newSigner(ctx)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := findAccounts(ctx, keychain, journal, indexes, stuck)
+	if err != nil {
+		return err
+	}
+
+	// Confirmation window
+	fmt.Println()
+	action := "Speed up"
+	if cancel {
+		action = "Cancel"
+	}
+
+	for _, data := range stuck {
+		replacement := manager.ReplacementTx(data, cancel, ctx.Uint64("bump"))
+		gasPrice := pkg.BigToDecimal(replacement.GasPrice()).Shift(-9)
+		fmt.Printf("- %s %s from %s (nonce %d), new gas price %s gwei\n", action, data.Tx.Hash().String(), data.From.String(), data.Tx.Nonce(), gasPrice.String())
+	}
+
+	fmt.Println()
+	fmt.Printf("Do you wish to proceed? [yes/no]: ")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if scanner.Text() != "yes" {
+		fmt.Printf("Operation aborted\n")
+		return nil
+	}
+
+	fmt.Println()
+
+	// Replace txs
+	total := 0
+	for i, data := range stuck {
+		signed, err := manager.Replace(signer, accounts[i], data, cancel, ctx.Uint64("bump"))
+		if err != nil {
+			return err
+		}
+
+		// Resume tracks replacement of sped up tx, cancelled one is done
+		if journal != nil {
+			if cancel {
+				err = journal.UpdateTx(indexes[i], pkg.JournalReplaced, 0)
+			} else {
+				err = journal.ReplaceTx(indexes[i], signed)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		total++
+	}
+
+	fmt.Printf("Replaced %d transactions of %d\n", total, len(stuck))
+	return nil
+}
+
+// Returns remote signer, private key signer or HD signer with its keychain
+func newSigner(ctx *cli.Context) (pkg.Signer, *pkg.Keychain, error) {
+	sources := 0
+	for _, name := range []string{"prv", "xprv", "mnemonic", "key-name"} {
+		if len(ctx.String(name)) > 0 {
+			sources++
+		}
+	}
+
+	if sources > 1 {
+		return nil, nil, errors.New("Private key, extended private key, mnemonic and vault key name are mutually exclusive")
+	}
+
+	if url := pkg.SignerURL(ctx.String("signer"), sources > 0); len(url) > 0 {
+		signer, err := pkg.NewRemoteSigner(url)
+		return signer, nil, err
+	}
+
+	if sources == 0 {
+		return nil, nil, errors.New("Please provide sender key using --prv, --xprv, --mnemonic, --key-name or --signer flag")
+	}
+
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xprv"),
+		KeyType:        pkg.KeyPrivate,
+		Insecure:       ctx.Bool("insecure"),
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
+	}
+
+	// Vault entry may hold either private key or HD key
+	if name := ctx.String("key-name"); len(name) > 0 {
+		entry, err := pkg.UnlockVaultEntry(ctx.String("vault"), ctx.String("vault-password"), name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if entry.Type == pkg.VaultPrv {
+			key, err := entry.PrivateKey()
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return pkg.NewKeySigner(key), nil, nil
+		}
+
+		key, err := entry.ExtendedKey()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !key.IsPrivate() {
+			return nil, nil, fmt.Errorf("Vault entry %q is not a private key", name)
+		}
+
+		path, err := input.DerivationPath()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keychain, err := pkg.NewFromKey(key, path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return pkg.NewHDSigner(keychain, 0, 0), keychain, nil
+	}
+
+	if prv := ctx.String("prv"); len(prv) > 0 {
+		raw, err := pkg.ReadKey(prv, "Enter private key: ", ctx.Bool("insecure"))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key, err := pkg.GetPrivateKey(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return pkg.NewKeySigner(key), nil, nil
+	}
+
+	keychain, err := input.Keychain()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pkg.NewHDSigner(keychain, 0, 0), keychain, nil
+}
+
+// Resolves signing account of every stuck tx, derived senders need index
+func findAccounts(ctx *cli.Context, keychain *pkg.Keychain, journal *pkg.Journal, indexes []int, stuck []*pkg.StuckTx) ([]pkg.Account, error) {
+	accounts := []pkg.Account{}
+	if keychain == nil {
+		for _, data := range stuck {
+			accounts = append(accounts, pkg.Account{Address: data.From})
+		}
+
+		return accounts, nil
+	}
+
+	// Derived senders of collector journal are known
+	if journal != nil && journal.Bundle.Kind == pkg.BundleCollect {
+		for _, i := range indexes {
+			accounts = append(accounts, journal.Bundle.Account(journal.Bundle.Transactions[i]))
+		}
+
+		return accounts, nil
+	}
+
+	from := ctx.Uint("from")
+	until := ctx.Uint("until")
+	if from > until {
+		return nil, errors.New("From should be greater than until")
+	}
+
+	senders := []common.Address{}
+	for _, data := range stuck {
+		senders = append(senders, data.From)
+	}
+
+	matches, err := keychain.Locate(senders, uint32(from), uint32(until), runtime.NumCPU())
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[common.Address]pkg.AddressInfo{}
+	for _, info := range matches {
+		found[common.HexToAddress(info.Address)] = info
+	}
+
+	for _, data := range stuck {
+		info, ok := found[data.From]
+		if !ok {
+			return nil, fmt.Errorf("Sender %s is not derived (in accounts %d to %d)", data.From.String(), from, until)
+		}
+
+		accounts = append(accounts, pkg.Account{Address: data.From, Index: info.Index, Path: info.Path})
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "sign"
+	app.Usage = "signs prepared transaction bundle offline"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "wei",
+			Usage: "output values in wei",
+		},
+		cli.StringFlag{
+			Name:  "bundle",
+			Usage: "unsigned transaction bundle file",
+		},
+		cli.StringFlag{
+			Name:  "output",
+			Usage: "signed transaction bundle file (default: <bundle>.signed.json)",
+		},
+		cli.StringFlag{
+			Name:  "xprv",
+			Usage: "account extended private key, for collect bundles (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.StringFlag{
+			Name:  "prv",
+			Usage: "source account private key, for distribute bundles (-, prompt, env:NAME, file:PATH or keystore:PATH)",
+		},
+		cli.StringFlag{
+			Name:  "xpub",
+			Usage: "account extended public key to verify recipients of distribute bundles (optional)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "key-name",
+			Usage: "name of signing key in vault",
+		},
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "vault-password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Parse CLI flags
+		path := ctx.String("bundle")
+		if len(path) == 0 {
+			return errors.New("Please provide transaction bundle using --bundle flag")
+		}
+
+		output := ctx.String("output")
+		if len(output) == 0 {
+			output = strings.TrimSuffix(path, ".json") + ".signed.json"
+		}
+
+		// Read bundle
+		bundle, err := pkg.ReadBundle(path)
+		if err != nil {
+			return err
+		}
+
+		if bundle.Signed() {
+			return errors.New("Bundle is already signed")
+		}
+
+		// Init signer and verify bundle accounts
+		var signer pkg.Signer
+		if bundle.Kind == pkg.BundleCollect {
+			signer, err = collectSigner(ctx, bundle)
+		} else {
+			signer, err = distributeSigner(ctx, bundle)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// Review
+		wei := ctx.Bool("wei")
+		bundle.Print(wei)
+		fmt.Println()
+		fmt.Printf("Do you wish to sign? [yes/no]: ")
+
+		// Scan for input
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		if scanner.Text() != "yes" {
+			fmt.Printf("Operation aborted\n")
+			return nil
+		}
+
+		// Sign & write
+		if err := bundle.Sign(signer); err != nil {
+			return err
+		}
+
+		if err := bundle.WriteFile(output); err != nil {
+			return err
+		}
+
+		fmt.Printf("Signed bundle written to %s\n", output)
+		return nil
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+// Collect bundles are signed by derived accounts
+func collectSigner(ctx *cli.Context, bundle *pkg.Bundle) (pkg.Signer, error) {
+	if len(ctx.String("prv")) > 0 || len(ctx.String("xpub")) > 0 {
+		return nil, errors.New("Collect bundles are signed with extended private key, please use --xprv, --mnemonic or --key-name flag")
+	}
+
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xprv"),
+		KeyType:        pkg.KeyPrivate,
+		Insecure:       ctx.Bool("insecure"),
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           bundle.Path,
+		KeyName:        ctx.String("key-name"),
+		VaultPath:      ctx.String("vault"),
+		VaultPassword:  ctx.String("vault-password"),
+	}
+
+	if input.IsEmpty() {
+		return nil, errors.New("Please provide account extended private key using --xprv, --mnemonic or --key-name flag")
+	}
+
+	keychain, err := input.Keychain()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bundle.VerifyKeychain(keychain); err != nil {
+		return nil, err
+	}
+
+	return pkg.NewHDSigner(keychain, 0, 0), nil
+}
+
+// Distribute bundles are signed by single source account
+func distributeSigner(ctx *cli.Context, bundle *pkg.Bundle) (pkg.Signer, error) {
+	if len(ctx.String("xprv")) > 0 {
+		return nil, errors.New("Distribute bundles are signed with private key, please use --prv or --key-name flag")
+	}
+
+	var key *ecdsa.PrivateKey
+	if name := ctx.String("key-name"); len(name) > 0 {
+		entry, err := pkg.UnlockVaultEntry(ctx.String("vault"), ctx.String("vault-password"), name)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err = entry.PrivateKey()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if len(ctx.String("prv")) == 0 {
+			return nil, errors.New("Please provide source account private key using --prv or --key-name flag")
+		}
+
+		raw, err := pkg.ReadKey(ctx.String("prv"), "Enter private key: ", ctx.Bool("insecure"))
+		if err != nil {
+			return nil, err
+		}
+
+		key, err = pkg.GetPrivateKey(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signer := pkg.NewKeySigner(key)
+	accounts, _ := signer.Accounts()
+	for i, tx := range bundle.Transactions {
+		if tx.From != accounts[0].Address {
+			return nil, fmt.Errorf("Transaction %d is sent from %s, but private key belongs to %s", i, tx.From.String(), accounts[0].Address.String())
+		}
+	}
+
+	// Recipients can only be verified with account key
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xpub"),
+		KeyType:        pkg.KeyPublic,
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           bundle.Path,
+	}
+
+	if input.IsEmpty() {
+		fmt.Println("Warning: recipients are not verified, provide --xpub or --mnemonic flag to verify them")
+		return signer, nil
+	}
+
+	keychain, err := input.Keychain()
+	if err != nil {
+		return nil, err
+	}
+
+	return signer, bundle.VerifyKeychain(keychain)
+}
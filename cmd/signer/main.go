@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "signer"
+	app.Usage = "serves Clef-style signing API for derived keys (local stand-in for Clef)"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.Uint64Flag{
+			Name:  "chain",
+			Usage: "Ethereum chain ID",
+			Value: 1,
+		},
+		cli.StringFlag{
+			Name:  "http",
+			Usage: "HTTP listen address",
+			Value: "127.0.0.1:8550",
+		},
+		cli.StringFlag{
+			Name:  "socket",
+			Usage: "unix socket path (instead of HTTP)",
+		},
+		cli.StringFlag{
+			Name:  "xprv",
+			Usage: "account extended private key (-, prompt, env:NAME or file:PATH)",
+		},
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+		cli.StringFlag{
+			Name:  "mnemonic",
+			Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+		},
+		cli.StringFlag{
+			Name:  "passphrase",
+			Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+		},
+		cli.StringFlag{
+			Name:  "prv",
+			Usage: "single private key (-, prompt, env:NAME, file:PATH or keystore:PATH), instead of extended key",
+		},
+		cli.StringFlag{
+			Name:  "key-name",
+			Usage: "name of extended private key, mnemonic or private key in vault",
+		},
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "vault-password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "derivation path template",
+			Value: pkg.DefaultPath,
+		},
+		cli.UintFlag{
+			Name:  "account",
+			Usage: "BIP-44 account used as {account} in path",
+			Value: 0,
+		},
+		cli.BoolFlag{
+			Name:  "internal",
+			Usage: "use internal (change) chain as {chain} in path",
+		},
+		cli.UintFlag{
+			Name:  "from",
+			Usage: "start account number",
+			Value: 0,
+		},
+		cli.UintFlag{
+			Name:  "until",
+			Usage: "final account number",
+			Value: 100,
+		},
+	}
+
+	app.Action = func(ctx *cli.Context) error {
+		// Init signer
+		signer, err := newSigner(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Init API
+		chainID := new(big.Int).SetUint64(ctx.Uint64("chain"))
+		api, err := pkg.NewSignerAPI(signer, chainID)
+		if err != nil {
+			return err
+		}
+
+		accounts, err := api.List()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Serving %d accounts for chain %d\n", len(accounts), chainID)
+		return api.Serve(ctx.String("http"), ctx.String("socket"))
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func newSigner(ctx *cli.Context) (pkg.Signer, error) {
+	from := ctx.Uint("from")
+	until := ctx.Uint("until")
+	if from > until {
+		return nil, errors.New("From should be greater than until")
+	}
+
+	input := pkg.KeychainInput{
+		Key:            ctx.String("xprv"),
+		KeyType:        pkg.KeyPrivate,
+		Insecure:       ctx.Bool("insecure"),
+		MnemonicFile:   ctx.String("mnemonic"),
+		PassphraseFile: ctx.String("passphrase"),
+		Path:           ctx.String("path"),
+		Account:        uint32(ctx.Uint("account")),
+		Internal:       ctx.Bool("internal"),
+	}
+
+	prv := ctx.String("prv")
+	name := ctx.String("key-name")
+	if !input.IsEmpty() && (len(prv) > 0 || len(name) > 0) || len(prv) > 0 && len(name) > 0 {
+		return nil, errors.New("Extended key, private key and vault key name are mutually exclusive")
+	}
+
+	// Single private key
+	if len(prv) > 0 {
+		raw, err := pkg.ReadKey(prv, "Enter private key: ", ctx.Bool("insecure"))
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := pkg.GetPrivateKey(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return pkg.NewKeySigner(key), nil
+	}
+
+	// Vault entry (private key, extended private key or mnemonic)
+	if len(name) > 0 {
+		entry, err := pkg.UnlockVaultEntry(ctx.String("vault"), ctx.String("vault-password"), name)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Type == pkg.VaultPrv {
+			key, err := entry.PrivateKey()
+			if err != nil {
+				return nil, err
+			}
+
+			return pkg.NewKeySigner(key), nil
+		}
+
+		key, err := entry.ExtendedKey()
+		if err != nil {
+			return nil, err
+		}
+
+		if !key.IsPrivate() {
+			return nil, errors.New("Expected extended private key (xprv), but got extended public key")
+		}
+
+		path, err := input.DerivationPath()
+		if err != nil {
+			return nil, err
+		}
+
+		keychain, err := pkg.NewFromKey(key, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return pkg.NewHDSigner(keychain, uint32(from), uint32(until)), nil
+	}
+
+	if input.IsEmpty() {
+		return nil, errors.New("Please provide account extended private key using --xprv, --mnemonic, --key-name or --prv flag")
+	}
+
+	keychain, err := input.Keychain()
+	if err != nil {
+		return nil, err
+	}
+
+	return pkg.NewHDSigner(keychain, uint32(from), uint32(until)), nil
+}
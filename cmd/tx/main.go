@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "tx"
+	app.Usage = "decodes and broadcasts signed raw transactions"
+	app.Version = "1.0.1"
+	app.Commands = []cli.Command{
+		{
+			Name:  "decode",
+			Usage: "decodes raw transactions and recovers senders",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "raw",
+					Usage: "hex-encoded signed transaction",
+				},
+				cli.StringFlag{
+					Name:  "file",
+					Usage: "file with raw transactions (one per line) or signed bundle",
+				},
+				cli.BoolFlag{
+					Name:  "wei",
+					Usage: "output values in wei",
+				},
+				cli.StringFlag{
+					Name:  "xpub",
+					Usage: "account extended public key (to check whether senders are derived addresses)",
+				},
+				cli.StringFlag{
+					Name:  "mnemonic",
+					Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME)",
+				},
+				cli.StringFlag{
+					Name:  "passphrase",
+					Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+				},
+				cli.StringFlag{
+					Name:  "path",
+					Usage: "derivation path template",
+					Value: pkg.DefaultPath,
+				},
+				cli.UintFlag{
+					Name:  "account",
+					Usage: "BIP-44 account used as {account} in path",
+					Value: 0,
+				},
+				cli.BoolFlag{
+					Name:  "internal",
+					Usage: "use internal (change) chain as {chain} in path",
+				},
+				cli.UintFlag{
+					Name:  "from",
+					Usage: "start account number to search senders in",
+					Value: 0,
+				},
+				cli.UintFlag{
+					Name:  "until",
+					Usage: "final account number to search senders in",
+					Value: 1000,
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				txs, err := readTxs(ctx)
+				if err != nil {
+					return err
+				}
+
+				input := pkg.KeychainInput{
+					Key:            ctx.String("xpub"),
+					KeyType:        pkg.KeyPublic,
+					MnemonicFile:   ctx.String("mnemonic"),
+					PassphraseFile: ctx.String("passphrase"),
+					Path:           ctx.String("path"),
+					Account:        uint32(ctx.Uint("account")),
+					Internal:       ctx.Bool("internal"),
+				}
+
+				// Locate senders among derived addresses
+				found := map[common.Address]pkg.AddressInfo{}
+				if !input.IsEmpty() {
+					from := ctx.Uint("from")
+					until := ctx.Uint("until")
+					if from > until {
+						return errors.New("From should be greater than until")
+					}
+
+					keychain, err := input.Keychain()
+					if err != nil {
+						return err
+					}
+
+					senders := []common.Address{}
+					for _, tx := range txs {
+						senders = append(senders, tx.From)
+					}
+
+					matches, err := keychain.Locate(senders, uint32(from), uint32(until), runtime.NumCPU())
+					if err != nil {
+						return err
+					}
+
+					for _, info := range matches {
+						found[common.HexToAddress(info.Address)] = info
+					}
+				}
+
+				for _, tx := range txs {
+					fmt.Println()
+					tx.Print(ctx.Bool("wei"))
+					if input.IsEmpty() {
+						continue
+					}
+
+					if info, ok := found[tx.From]; ok {
+						fmt.Printf("Sender: derived account %d (%s)\n", info.Index, info.Path)
+					} else {
+						fmt.Printf("Sender: not derived (in accounts %d to %d)\n", ctx.Uint("from"), ctx.Uint("until"))
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Name:  "broadcast",
+			Usage: "submits raw transactions to node",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "rpc",
+					Usage: "Ethereum node RPC URL",
+				},
+				cli.StringFlag{
+					Name:  "raw",
+					Usage: "hex-encoded signed transaction",
+				},
+				cli.StringFlag{
+					Name:  "file",
+					Usage: "file with raw transactions (one per line) or signed bundle",
+				},
+				cli.BoolFlag{
+					Name:  "wei",
+					Usage: "output values in wei",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				rpc := ctx.String("rpc")
+				if len(rpc) == 0 {
+					return errors.New("Please provide RPC URL using --rpc flag")
+				}
+
+				txs, err := readTxs(ctx)
+				if err != nil {
+					return err
+				}
+
+				// Init manager with node chain ID
+				manager, err := pkg.NewManager(rpc, 0, 0, ctx.Bool("wei"))
+				if err != nil {
+					return err
+				}
+
+				manager.ChainID, err = manager.NodeChainID()
+				if err != nil {
+					return err
+				}
+
+				// Broadcast
+				total, err := manager.SendRawTxs(txs)
+				fmt.Printf("Sent %d transactions of %d\n", total, len(txs))
+				return err
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func readTxs(ctx *cli.Context) ([]*pkg.RawTx, error) {
+	raw := ctx.String("raw")
+	file := ctx.String("file")
+	if len(raw) > 0 && len(file) > 0 {
+		return nil, errors.New("Please use either --raw or --file flag")
+	}
+
+	if len(raw) > 0 {
+		tx, err := pkg.DecodeRawTx(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*pkg.RawTx{tx}, nil
+	}
+
+	if len(file) > 0 {
+		return pkg.ReadRawTxs(file)
+	}
+
+	return nil, errors.New("Please provide raw transaction using --raw or --file flag")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "vault"
+	app.Usage = "stores extended keys, mnemonics and private keys in encrypted file"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "vault",
+			Usage:  "vault file (default: ~/.ethereum-hd-tools/vault.json)",
+			EnvVar: "HD_VAULT",
+		},
+		cli.StringFlag{
+			Name:  "password",
+			Usage: "vault password source (-, prompt, env:NAME or file:PATH)",
+			Value: "prompt",
+		},
+	}
+	app.Commands = []cli.Command{
+		{
+			Name:  "init",
+			Usage: "creates empty vault",
+			Action: func(ctx *cli.Context) error {
+				password, err := pkg.ReadSecret(ctx.GlobalString("password"), "Enter new vault password: ")
+				if err != nil {
+					return err
+				}
+
+				// Ask twice when typed interactively
+				if ctx.GlobalString("password") == "prompt" {
+					confirm, err := pkg.ReadSecret("prompt", "Repeat vault password: ")
+					if err != nil {
+						return err
+					}
+
+					if confirm != password {
+						return errors.New("Passwords do not match")
+					}
+				}
+
+				path := ctx.GlobalString("vault")
+				if len(path) == 0 {
+					path = pkg.DefaultVaultPath()
+				}
+
+				if _, err := pkg.CreateVault(path, password); err != nil {
+					return err
+				}
+
+				fmt.Printf("Created vault %s\n", path)
+				return nil
+			},
+		},
+		{
+			Name:  "add",
+			Usage: "adds named key to vault",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "key name",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Usage: "key type (" + strings.Join(pkg.VaultTypes, ", ") + ")",
+				},
+				cli.StringFlag{
+					Name:  "secret",
+					Usage: "key or mnemonic source (-, prompt, env:NAME, file:PATH or keystore:PATH)",
+					Value: "prompt",
+				},
+				cli.StringFlag{
+					Name:  "passphrase",
+					Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				name := ctx.String("name")
+				if len(name) == 0 {
+					return errors.New("Please provide key name using --name flag")
+				}
+
+				kind := ctx.String("type")
+				if len(kind) == 0 {
+					return errors.New("Please provide key type using --type flag")
+				}
+
+				vault, err := pkg.UnlockVault(ctx.GlobalString("vault"), ctx.GlobalString("password"))
+				if err != nil {
+					return err
+				}
+
+				secret, err := pkg.ReadKey(ctx.String("secret"), "Enter "+kind+": ", false)
+				if err != nil {
+					return err
+				}
+
+				passphrase := ""
+				if len(ctx.String("passphrase")) > 0 {
+					if kind != pkg.VaultMnemonic {
+						return errors.New("Passphrase is only supported for mnemonics")
+					}
+
+					passphrase, err = pkg.ReadSecret(ctx.String("passphrase"), "Enter passphrase: ")
+					if err != nil {
+						return err
+					}
+				}
+
+				entry := pkg.VaultEntry{
+					Name:       name,
+					Type:       kind,
+					Secret:     strings.TrimSpace(secret),
+					Passphrase: passphrase,
+					Created:    time.Now().UTC(),
+				}
+
+				if err := vault.Add(entry); err != nil {
+					return err
+				}
+
+				if err := vault.Save(); err != nil {
+					return err
+				}
+
+				fmt.Printf("Added %s key %q\n", kind, name)
+				return nil
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "lists stored keys",
+			Action: func(ctx *cli.Context) error {
+				vault, err := pkg.UnlockVault(ctx.GlobalString("vault"), ctx.GlobalString("password"))
+				if err != nil {
+					return err
+				}
+
+				if len(vault.Entries) == 0 {
+					fmt.Println("Vault is empty")
+					return nil
+				}
+
+				for _, entry := range vault.Entries {
+					fmt.Printf("%-20s %-10s %s\n", entry.Name, entry.Type, entry.Created.Format(time.RFC3339))
+				}
+
+				return nil
+			},
+		},
+		{
+			Name:  "remove",
+			Usage: "removes named key from vault",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "key name",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				name := ctx.String("name")
+				if len(name) == 0 {
+					return errors.New("Please provide key name using --name flag")
+				}
+
+				vault, err := pkg.UnlockVault(ctx.GlobalString("vault"), ctx.GlobalString("password"))
+				if err != nil {
+					return err
+				}
+
+				if err := vault.Remove(name); err != nil {
+					return err
+				}
+
+				if err := vault.Save(); err != nil {
+					return err
+				}
+
+				fmt.Printf("Removed key %q\n", name)
+				return nil
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/pavel-main/ethereum-hd-tools/pkg"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	// Init CLI
+	app := cli.NewApp()
+	app.Name = "xkey"
+	app.Usage = "inspects, neuters and derives extended keys"
+	app.Version = "1.0.1"
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "insecure",
+			Usage: "allow private keys as literal command line values",
+		},
+	}
+	app.Commands = []cli.Command{
+		{
+			Name:      "inspect",
+			Usage:     "decodes extended key",
+			ArgsUsage: "<key (-, prompt, env:NAME or file:PATH)>",
+			Action: func(ctx *cli.Context) error {
+				key, err := parseKey(ctx)
+				if err != nil {
+					return err
+				}
+
+				info, err := pkg.InspectKey(key)
+				if err != nil {
+					return err
+				}
+
+				info.Print()
+				return nil
+			},
+		},
+		{
+			Name:      "neuter",
+			Usage:     "converts extended private key into extended public key",
+			ArgsUsage: "<key (-, prompt, env:NAME or file:PATH)>",
+			Action: func(ctx *cli.Context) error {
+				key, err := parseKey(ctx)
+				if err != nil {
+					return err
+				}
+
+				if !key.IsPrivate() {
+					return errors.New("Extended key is already public")
+				}
+
+				public, err := key.Neuter()
+				if err != nil {
+					return err
+				}
+
+				fmt.Println(public.String())
+				return nil
+			},
+		},
+		{
+			Name:      "derive",
+			Usage:     "derives child extended key at given path",
+			ArgsUsage: "<key (-, prompt, env:NAME or file:PATH)>",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "path",
+					Usage: "derivation path (e.g. m/44'/60'/0')",
+				},
+				cli.StringFlag{
+					Name:  "mnemonic",
+					Usage: "BIP-39 mnemonic source (file path, -, prompt or env:NAME), instead of key",
+				},
+				cli.StringFlag{
+					Name:  "passphrase",
+					Usage: "BIP-39 passphrase source (file path, -, prompt or env:NAME), optional",
+				},
+				cli.BoolFlag{
+					Name:  "neuter",
+					Usage: "output extended public key",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				raw := ctx.String("path")
+				if len(raw) == 0 {
+					return errors.New("Please provide derivation path using --path flag")
+				}
+
+				path, err := pkg.ParseFixedPath(raw)
+				if err != nil {
+					return err
+				}
+
+				var key *hdkeychain.ExtendedKey
+				if len(ctx.String("mnemonic")) > 0 {
+					input := pkg.KeychainInput{
+						MnemonicFile:   ctx.String("mnemonic"),
+						PassphraseFile: ctx.String("passphrase"),
+					}
+					key, err = input.ExtendedKey()
+				} else {
+					key, err = parseKey(ctx)
+				}
+
+				if err != nil {
+					return err
+				}
+
+				child, err := pkg.DeriveChildKey(key, path)
+				if err != nil {
+					return err
+				}
+
+				if ctx.Bool("neuter") {
+					child, err = child.Neuter()
+					if err != nil {
+						return err
+					}
+				}
+
+				fmt.Println(child.String())
+				return nil
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Printf("Error: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+func parseKey(ctx *cli.Context) (*hdkeychain.ExtendedKey, error) {
+	arg := ctx.Args().First()
+	if len(arg) == 0 {
+		return nil, errors.New("Please provide extended key as an argument")
+	}
+
+	raw, err := pkg.ReadKey(arg, "Enter extended key: ", true)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hdkeychain.NewKeyFromString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extended public keys are fine on command line
+	if raw == arg && key.IsPrivate() && !ctx.GlobalBool("insecure") {
+		return nil, errors.New("Refusing to read extended private key from command line, please use -, prompt, env:NAME or file:PATH source (or --insecure flag)")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,324 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	BundleVersion = 1
+
+	// Bundle kinds
+	BundleCollect    = "collect"    // derived accounts send to single destination
+	BundleDistribute = "distribute" // single account sends to derived accounts
+)
+
+// Transactions prepared online and signed offline
+type Bundle struct {
+	Version      int        `json:"version"`
+	Kind         string     `json:"kind"`
+	ChainID      *big.Int   `json:"chainId"`
+	Path         string     `json:"path"` // Derivation path template of derived accounts
+	Created      time.Time  `json:"created"`
+	Transactions []BundleTx `json:"transactions"`
+	Summary      *Summary   `json:"summary,omitempty"` // Informational, filled when writing file
+}
+
+type Summary struct {
+	Transactions int      `json:"transactions"`
+	Signed       bool     `json:"signed"`
+	Value        *big.Int `json:"value"`
+	Fees         *big.Int `json:"fees"`
+}
+
+type BundleTx struct {
+	Index     uint32         `json:"index"` // Derived account (sender when collecting, recipient when distributing)
+	Path      string         `json:"path"`
+	From      common.Address `json:"from"`
+	To        common.Address `json:"to"`
+	Nonce     uint64         `json:"nonce"`
+	Value     *big.Int       `json:"value"`
+	Gas       uint64         `json:"gas"`
+	GasPrice  *big.Int       `json:"gasPrice"`                       // Max fee per gas for dynamic fee transactions
+	GasTipCap *big.Int       `json:"maxPriorityFeePerGas,omitempty"` // Set for dynamic fee (EIP-1559) transactions only
+	Raw       hexutil.Bytes  `json:"raw,omitempty"`                  // Signed transaction
+	Hash      *common.Hash   `json:"hash,omitempty"`
+}
+
+func NewBundle(kind string, chainID *big.Int, keychain *Keychain) *Bundle {
+	return &Bundle{
+		Version:      BundleVersion,
+		Kind:         kind,
+		ChainID:      chainID,
+		Path:         keychain.Path.String(),
+		Created:      time.Now().UTC(),
+		Transactions: []BundleTx{},
+	}
+}
+
+func ReadBundle(path string) (*Bundle, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseBundle(data)
+}
+
+func ParseBundle(data []byte) (*Bundle, error) {
+	bundle := &Bundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, err
+	}
+
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+func (b *Bundle) WriteFile(path string) error {
+	value, fees := b.Totals()
+	b.Summary = &Summary{Transactions: len(b.Transactions), Signed: b.Signed(), Value: value, Fees: fees}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Dynamic fee transactions pay base fee plus tip, capped by GasPrice
+func (tx BundleTx) DynamicFee() bool {
+	return tx.GasTipCap != nil
+}
+
+// Unsigned legacy transaction
+func (tx BundleTx) Transaction() *types.Transaction {
+	return types.NewTransaction(tx.Nonce, tx.To, tx.Value, tx.Gas, tx.GasPrice, nil)
+}
+
+// Unsigned dynamic fee transaction
+func (tx BundleTx) DynamicFeeTransaction(chainID *big.Int) *DynamicFeeTx {
+	return NewDynamicFeeTx(chainID, tx.Nonce, tx.To, tx.Value, tx.Gas, tx.GasTipCap, tx.GasPrice, nil)
+}
+
+// Signed transaction
+func (tx BundleTx) SignedTransaction() (Transaction, error) {
+	if len(tx.Raw) == 0 {
+		return nil, errors.New("Transaction is not signed")
+	}
+
+	return DecodeTransaction(tx.Raw)
+}
+
+// Checks that signed transaction matches fields and is signed by sender
+func (tx BundleTx) verify(chainID *big.Int, signed Transaction) error {
+	var sender common.Address
+	var err error
+	if signedChainID := signedChainID(signed); signedChainID.Cmp(chainID) != 0 {
+		return fmt.Errorf("signed for chain %s instead of %s", signedChainID.String(), chainID.String())
+	}
+
+	if tx.DynamicFee() {
+		dynamic, ok := signed.(*DynamicFeeTx)
+		if !ok || dynamic.SigningHash() != tx.DynamicFeeTransaction(chainID).SigningHash() {
+			return errors.New("signed transaction doesn't match bundle")
+		}
+
+		sender, err = dynamic.Sender()
+	} else {
+		legacy, ok := signed.(*types.Transaction)
+		signer := types.NewEIP155Signer(chainID)
+		if !ok || signer.Hash(legacy) != signer.Hash(tx.Transaction()) {
+			return errors.New("signed transaction doesn't match bundle")
+		}
+
+		sender, err = types.Sender(signer, legacy)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if sender != tx.From {
+		return fmt.Errorf("signed by %s instead of %s", sender.String(), tx.From.String())
+	}
+
+	return nil
+}
+
+func signedChainID(signed Transaction) *big.Int {
+	if dynamic, ok := signed.(*DynamicFeeTx); ok {
+		return dynamic.ChainID()
+	}
+
+	return signed.(*types.Transaction).ChainId()
+}
+
+// Signing account, index is only known for derived senders
+func (b *Bundle) Account(tx BundleTx) Account {
+	if b.Kind == BundleCollect {
+		return Account{Address: tx.From, Index: tx.Index, Path: tx.Path}
+	}
+
+	return Account{Address: tx.From}
+}
+
+func (b *Bundle) Signed() bool {
+	for _, tx := range b.Transactions {
+		if len(tx.Raw) == 0 {
+			return false
+		}
+	}
+
+	return len(b.Transactions) > 0
+}
+
+// Checks format and, for signed transactions, that they match bundle fields
+func (b *Bundle) Validate() error {
+	if b.Version != BundleVersion {
+		return fmt.Errorf("Unsupported bundle version %d", b.Version)
+	}
+
+	if b.Kind != BundleCollect && b.Kind != BundleDistribute {
+		return fmt.Errorf("Unknown bundle kind %q", b.Kind)
+	}
+
+	if b.ChainID == nil || b.ChainID.Sign() <= 0 {
+		return errors.New("Bundle has invalid chain ID")
+	}
+
+	for i, tx := range b.Transactions {
+		if tx.Value == nil || tx.GasPrice == nil || tx.Value.Sign() < 0 || tx.GasPrice.Sign() < 0 {
+			return fmt.Errorf("Transaction %d has invalid value or gas price", i)
+		}
+
+		if tx.DynamicFee() && (tx.GasTipCap.Sign() < 0 || tx.GasTipCap.Cmp(tx.GasPrice) > 0) {
+			return fmt.Errorf("Transaction %d has priority fee above max fee", i)
+		}
+
+		if len(tx.Raw) == 0 {
+			continue
+		}
+
+		signed, err := tx.SignedTransaction()
+		if err != nil {
+			return fmt.Errorf("Transaction %d: %s", i, err.Error())
+		}
+
+		if err := tx.verify(b.ChainID, signed); err != nil {
+			return fmt.Errorf("Transaction %d: %s", i, err.Error())
+		}
+
+		// Hash may be omitted by other tools (or hand edits), it is derived from raw transaction
+		hash := signed.Hash()
+		if tx.Hash == nil {
+			b.Transactions[i].Hash = &hash
+		} else if *tx.Hash != hash {
+			return fmt.Errorf("Transaction %d: hash mismatch", i)
+		}
+	}
+
+	return nil
+}
+
+// Checks derived accounts against keychain
+func (b *Bundle) VerifyKeychain(keychain *Keychain) error {
+	if path := keychain.Path.String(); path != b.Path {
+		return fmt.Errorf("Bundle derivation path %s doesn't match %s", b.Path, path)
+	}
+
+	for i, tx := range b.Transactions {
+		derived, err := keychain.DeriveAddress(tx.Index)
+		if err != nil {
+			return err
+		}
+
+		address := tx.To
+		if b.Kind == BundleCollect {
+			address = tx.From
+		}
+
+		if derived.Address != address {
+			return fmt.Errorf("Transaction %d: account %d derives to %s, not %s", i, tx.Index, derived.Address.String(), address.String())
+		}
+	}
+
+	return nil
+}
+
+// Signs all transactions for bundle chain ID (legacy ones with EIP-155 signer)
+func (b *Bundle) Sign(signer Signer) error {
+	for i := range b.Transactions {
+		tx := &b.Transactions[i]
+		var signed Transaction
+		var err error
+		if tx.DynamicFee() {
+			signed, err = signer.SignDynamicFeeTx(b.Account(*tx), tx.DynamicFeeTransaction(b.ChainID))
+		} else {
+			signed, err = signer.SignTx(b.Account(*tx), tx.Transaction(), b.ChainID)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		raw, err := EncodeTransaction(signed)
+		if err != nil {
+			return err
+		}
+
+		hash := signed.Hash()
+		tx.Raw = raw
+		tx.Hash = &hash
+	}
+
+	return nil
+}
+
+// Returns total transferred value and total fees (maximum possible ones for
+// dynamic fee transactions, actual ones are known from receipts)
+func (b *Bundle) Totals() (*big.Int, *big.Int) {
+	value := new(big.Int)
+	fees := new(big.Int)
+	for _, tx := range b.Transactions {
+		value.Add(value, tx.Value)
+		fees.Add(fees, new(big.Int).Mul(tx.GasPrice, new(big.Int).SetUint64(tx.Gas)))
+	}
+
+	return value, fees
+}
+
+func (b *Bundle) Print(wei bool) {
+	units := Units(wei)
+	value, fees := b.Totals()
+
+	fmt.Println()
+	fmt.Printf("Bundle: %s, %d transactions, chain ID %s\n", b.Kind, len(b.Transactions), b.ChainID.String())
+	fmt.Printf("Derivation path: %s\n", b.Path)
+	for _, tx := range b.Transactions {
+		printValue := WeiOrEther(tx.Value, wei)
+		fmt.Printf("- %s: %s -> %s, %s %s (nonce %d)\n", tx.Path, tx.From.String(), tx.To.String(), printValue.String(), units, tx.Nonce)
+	}
+
+	fmt.Printf("Total value: %s %s\n", WeiOrEther(value, wei).String(), units)
+	if len(b.Transactions) > 0 && b.Transactions[0].DynamicFee() {
+		tx := b.Transactions[0]
+		fmt.Printf("Max fee per gas: %s gwei, priority fee: %s gwei\n", GweiString(tx.GasPrice), GweiString(tx.GasTipCap))
+		fmt.Printf("Max total fees: %s %s\n", WeiOrEther(fees, wei).String(), units)
+		return
+	}
+
+	fmt.Printf("Total fees: %s %s\n", WeiOrEther(fees, wei).String(), units)
+}
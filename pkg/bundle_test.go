@@ -0,0 +1,175 @@
+package pkg
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BIP-39 test mnemonic (all-zero entropy)
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func testKeychain(t *testing.T, template string) *Keychain {
+	t.Helper()
+	path, err := ParsePath(template, 0, ExternalChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keychain, err := NewFromMnemonic(testMnemonic, "", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keychain
+}
+
+// Collect bundle of two accounts, signed for chain 1
+func testSignedBundle(t *testing.T, keychain *Keychain, dynamicFee bool) *Bundle {
+	t.Helper()
+	bundle := NewBundle(BundleCollect, big.NewInt(1), keychain)
+	for i := uint32(0); i < 2; i++ {
+		derived, err := keychain.DeriveAddress(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx := BundleTx{
+			Index:    i,
+			Path:     keychain.DerivationPath(i),
+			From:     derived.Address,
+			To:       common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87"),
+			Nonce:    uint64(i),
+			Value:    big.NewInt(1000),
+			Gas:      TransferGas,
+			GasPrice: big.NewInt(2000000000),
+		}
+
+		if dynamicFee {
+			tx.GasTipCap = big.NewInt(1000000000)
+		}
+
+		bundle.Transactions = append(bundle.Transactions, tx)
+	}
+
+	if err := bundle.Sign(NewHDSigner(keychain, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	return bundle
+}
+
+func TestBundleValidate(t *testing.T) {
+	keychain := testKeychain(t, DefaultPath)
+	other, err := keychain.DeriveAddress(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(b *Bundle)
+		err    string // Expected error substring, valid if empty
+	}{
+		{"valid", func(b *Bundle) {}, ""},
+		{"chain ID mismatch", func(b *Bundle) { b.ChainID = big.NewInt(5) }, "signed for chain 1 instead of 5"},
+		{"invalid chain ID", func(b *Bundle) { b.ChainID = big.NewInt(0) }, "invalid chain ID"},
+		{"value mismatch", func(b *Bundle) { b.Transactions[1].Value = big.NewInt(1001) }, "doesn't match bundle"},
+		{"recipient mismatch", func(b *Bundle) { b.Transactions[0].To = other.Address }, "doesn't match bundle"},
+		{"nonce mismatch", func(b *Bundle) { b.Transactions[0].Nonce = 7 }, "doesn't match bundle"},
+		{"sender mismatch", func(b *Bundle) { b.Transactions[0].From = other.Address }, "signed by"},
+		{"hash mismatch", func(b *Bundle) { b.Transactions[1].Hash = b.Transactions[0].Hash }, "hash mismatch"},
+		{"corrupted raw transaction", func(b *Bundle) { b.Transactions[0].Raw = b.Transactions[0].Raw[:20] }, "Transaction 0"},
+		{"missing value", func(b *Bundle) { b.Transactions[0].Value = nil }, "invalid value or gas price"},
+		{"unknown kind", func(b *Bundle) { b.Kind = "sweep" }, "Unknown bundle kind"},
+		{"unsupported version", func(b *Bundle) { b.Version = 2 }, "Unsupported bundle version"},
+	}
+
+	for _, dynamicFee := range []bool{false, true} {
+		for _, test := range tests {
+			bundle := testSignedBundle(t, keychain, dynamicFee)
+			test.modify(bundle)
+			err := bundle.Validate()
+			switch {
+			case len(test.err) == 0 && err != nil:
+				t.Errorf("%s (dynamic fee %t): unexpected error: %v", test.name, dynamicFee, err)
+			case len(test.err) > 0 && err == nil:
+				t.Errorf("%s (dynamic fee %t): expected error", test.name, dynamicFee)
+			case len(test.err) > 0 && !strings.Contains(err.Error(), test.err):
+				t.Errorf("%s (dynamic fee %t): error %q, want %q", test.name, dynamicFee, err.Error(), test.err)
+			}
+		}
+	}
+}
+
+func TestBundleValidateTransactionType(t *testing.T) {
+	keychain := testKeychain(t, DefaultPath)
+	for _, dynamicFee := range []bool{false, true} {
+		// Same fields and sender, but signed as transaction of other type
+		bundle := testSignedBundle(t, keychain, dynamicFee)
+		other := testSignedBundle(t, keychain, !dynamicFee)
+		bundle.Transactions[0].Raw = other.Transactions[0].Raw
+		bundle.Transactions[0].Hash = nil
+
+		if err := bundle.Validate(); err == nil || !strings.Contains(err.Error(), "doesn't match bundle") {
+			t.Errorf("dynamic fee %t: expected transaction mismatch, got %v", dynamicFee, err)
+		}
+	}
+}
+
+func TestBundleValidateDerivesMissingHash(t *testing.T) {
+	keychain := testKeychain(t, DefaultPath)
+	for _, dynamicFee := range []bool{false, true} {
+		bundle := testSignedBundle(t, keychain, dynamicFee)
+		want := *bundle.Transactions[0].Hash
+		bundle.Transactions[0].Hash = nil
+
+		if err := bundle.Validate(); err != nil {
+			t.Fatalf("dynamic fee %t: unexpected error: %v", dynamicFee, err)
+		}
+
+		if got := bundle.Transactions[0].Hash; got == nil || *got != want {
+			t.Errorf("dynamic fee %t: hash %v, want %s", dynamicFee, got, want.String())
+		}
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	keychain := testKeychain(t, DefaultPath)
+	bundle := testSignedBundle(t, keychain, true)
+	path := t.TempDir() + "/bundle.json"
+	if err := bundle.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadBundle(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	if !read.Signed() || len(read.Transactions) != 2 || *read.Transactions[1].Hash != *bundle.Transactions[1].Hash {
+		t.Errorf("bundle changed after round trip")
+	}
+}
+
+func TestBundleVerifyKeychain(t *testing.T) {
+	keychain := testKeychain(t, DefaultPath)
+	bundle := testSignedBundle(t, keychain, false)
+	if err := bundle.VerifyKeychain(keychain); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Derived address mismatch
+	bundle.Transactions[1].Index = 7
+	if err := bundle.VerifyKeychain(keychain); err == nil || !strings.Contains(err.Error(), "derives to") {
+		t.Errorf("expected derived address mismatch, got %v", err)
+	}
+
+	// Path mismatch
+	ledger := testKeychain(t, "m/44'/60'/{index}'/0/0")
+	if err := testSignedBundle(t, keychain, false).VerifyKeychain(ledger); err == nil || !strings.Contains(err.Error(), "derivation path") {
+		t.Errorf("expected derivation path mismatch, got %v", err)
+	}
+}
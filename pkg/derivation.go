@@ -0,0 +1,230 @@
+package pkg
+
+import (
+	"container/list"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// Maximum number of public keys kept in keychain cache
+	DefaultCacheSize = 100000
+
+	// Number of keys derived ahead by iterator
+	iteratorBatchSize = 256
+)
+
+type DerivedKey struct {
+	Index   uint32
+	Key     *btcec.PublicKey
+	Address common.Address
+}
+
+// Bounded LRU cache of derived public keys, keyed by derivation path
+type keyCache struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	path string
+	key  DerivedKey
+}
+
+func newKeyCache(size int) *keyCache {
+	return &keyCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *keyCache) Get(path string) (DerivedKey, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[path]
+	if !ok {
+		return DerivedKey{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).key, true
+}
+
+func (c *keyCache) Add(path string, key DerivedKey) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[path]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[path] = c.order.PushFront(&cacheEntry{path: path, key: key})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).path)
+	}
+}
+
+// Derives public key and address, using cache if possible
+func (k *Keychain) DeriveAddress(index uint32) (DerivedKey, error) {
+	path := k.DerivationPath(index)
+	if key, ok := k.cache.Get(path); ok {
+		return key, nil
+	}
+
+	key, err := k.derivePublic(index)
+	if err != nil {
+		return DerivedKey{}, err
+	}
+
+	k.cache.Add(path, key)
+	return key, nil
+}
+
+// Derives public key and address, bypassing cache
+func (k *Keychain) derivePublic(index uint32) (DerivedKey, error) {
+	child, err := k.DeriveKey(index)
+	if err != nil {
+		return DerivedKey{}, err
+	}
+
+	key, err := child.ECPubKey()
+	if err != nil {
+		return DerivedKey{}, err
+	}
+
+	address := crypto.PubkeyToAddress(*key.ToECDSA())
+	return DerivedKey{Index: index, Key: key, Address: address}, nil
+}
+
+// Calls fn for every position in [0, n) using all available CPU cores,
+// stops at first error
+func parallel(n int, fn func(i int) error) error {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+
+	var (
+		wg      sync.WaitGroup
+		once    sync.Once
+		failed  int32
+		next    = int64(-1)
+		failure error
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&failed) == 0 {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						failure = err
+						atomic.StoreInt32(&failed, 1)
+					})
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return failure
+}
+
+// Streams derived keys in range [from, until], deriving batches ahead
+// in parallel, so that large ranges are never held in memory at once
+type KeyIterator struct {
+	results chan DerivedKey
+	errors  chan error
+	quit    chan struct{}
+	once    sync.Once
+	current DerivedKey
+	err     error
+}
+
+func (k *Keychain) Iterate(from, until uint32) *KeyIterator {
+	it := &KeyIterator{
+		results: make(chan DerivedKey, iteratorBatchSize),
+		errors:  make(chan error, 1),
+		quit:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(it.results)
+		for start := uint64(from); start <= uint64(until); start += iteratorBatchSize {
+			end := start + iteratorBatchSize - 1
+			if end > uint64(until) {
+				end = uint64(until)
+			}
+
+			batch := make([]DerivedKey, end-start+1)
+			err := parallel(len(batch), func(i int) error {
+				key, err := k.DeriveAddress(uint32(start) + uint32(i))
+				batch[i] = key
+				return err
+			})
+
+			if err != nil {
+				it.errors <- err
+				return
+			}
+
+			for _, key := range batch {
+				select {
+				case it.results <- key:
+				case <-it.quit:
+					return
+				}
+			}
+		}
+	}()
+
+	return it
+}
+
+func (it *KeyIterator) Next() bool {
+	key, ok := <-it.results
+	if !ok {
+		select {
+		case it.err = <-it.errors:
+		default:
+		}
+		return false
+	}
+
+	it.current = key
+	return true
+}
+
+func (it *KeyIterator) Key() DerivedKey {
+	return it.current
+}
+
+func (it *KeyIterator) Err() error {
+	return it.err
+}
+
+// Stops background derivation, should be called if iteration is aborted early
+func (it *KeyIterator) Close() {
+	it.once.Do(func() {
+		close(it.quit)
+	})
+}
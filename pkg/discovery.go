@@ -0,0 +1,43 @@
+package pkg
+
+import "math/big"
+
+type Scheme struct {
+	Name string
+	Path string
+}
+
+// Derivation schemes used by common Ethereum wallets
+var Schemes = []Scheme{
+	{Name: "BIP-44 (MetaMask, Trezor, MyCrypto)", Path: "m/44'/60'/0'/0/{index}"},
+	{Name: "BIP-44 change chain", Path: "m/44'/60'/0'/1/{index}"},
+	{Name: "Ledger Live", Path: "m/44'/60'/{index}'/0/0"},
+	{Name: "Legacy MEW / Ledger", Path: "m/44'/60'/0'/{index}"},
+	{Name: "Ethereum Classic", Path: "m/44'/61'/0'/0/{index}"},
+	{Name: "Ethereum Classic (legacy MEW)", Path: "m/44'/61'/0'/{index}"},
+	{Name: "Ethereum Classic (legacy Ledger)", Path: "m/44'/60'/160720'/0'/{index}"},
+	{Name: "Testnet (coin type 1)", Path: "m/44'/1'/0'/0/{index}"},
+}
+
+// Scans addresses starting from given index, until gap consecutive
+// addresses have zero balance and zero nonce (BIP-44 gap limit)
+func (m *Manager) ScanUsed(keychain *Keychain, from, gap uint) (*Result, error) {
+	data := []TxData{}
+	total := BigZero
+
+	lastUsed, err := m.scanAccounts(keychain, from, 0, gap, func(account TxData) error {
+		if account.Balance.Cmp(BigZero) > 0 || account.Nonce > 0 {
+			total = new(big.Int).Add(total, account.Balance)
+			data = append(data, account)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Total: total, Data: data, GasCost: m.GasCost, LastUsed: lastUsed}
+	return result, nil
+}
@@ -0,0 +1,232 @@
+package pkg
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// EIP-2718 type of EIP-1559 transactions
+const DynamicFeeTxType = 0x02
+
+// Transaction fields common to legacy and dynamic fee transactions
+type Transaction interface {
+	Nonce() uint64
+	To() *common.Address
+	Value() *big.Int
+	Gas() uint64
+	GasPrice() *big.Int // Max fee per gas for dynamic fee transactions
+	Data() []byte
+	Hash() common.Hash
+}
+
+// EIP-2930 access list entry
+type AccessTuple struct {
+	Address     common.Address
+	StorageKeys []common.Hash
+}
+
+// EIP-1559 transaction (vendored go-ethereum only supports legacy ones)
+type DynamicFeeTx struct {
+	data dynamicFeeTxData
+}
+
+type dynamicFeeTxData struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"`
+	Value      *big.Int
+	Data       []byte
+	AccessList []AccessTuple
+	V, R, S    *big.Int
+}
+
+// Fields covered by signature
+type dynamicFeeTxUnsigned struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"`
+	Value      *big.Int
+	Data       []byte
+	AccessList []AccessTuple
+}
+
+func NewDynamicFeeTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gas uint64, gasTipCap, gasFeeCap *big.Int, data []byte) *DynamicFeeTx {
+	if data == nil {
+		data = []byte{}
+	}
+
+	return &DynamicFeeTx{data: dynamicFeeTxData{
+		ChainID:    new(big.Int).Set(chainID),
+		Nonce:      nonce,
+		GasTipCap:  new(big.Int).Set(gasTipCap),
+		GasFeeCap:  new(big.Int).Set(gasFeeCap),
+		Gas:        gas,
+		To:         &to,
+		Value:      new(big.Int).Set(value),
+		Data:       data,
+		AccessList: []AccessTuple{},
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+	}}
+}
+
+// Decodes EIP-2718 envelope (type byte followed by RLP of signed fields)
+func DecodeDynamicFeeTx(raw []byte) (*DynamicFeeTx, error) {
+	if len(raw) == 0 || raw[0] != DynamicFeeTxType {
+		return nil, errors.New("Not a dynamic fee transaction")
+	}
+
+	tx := new(DynamicFeeTx)
+	if err := rlp.DecodeBytes(raw[1:], &tx.data); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Decodes signed legacy or dynamic fee transaction
+func DecodeTransaction(raw []byte) (Transaction, error) {
+	if len(raw) > 0 && raw[0] == DynamicFeeTxType {
+		tx, err := DecodeDynamicFeeTx(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return tx, nil
+	}
+
+	// Other typed transactions (legacy ones start with RLP list prefix)
+	if len(raw) > 0 && raw[0] < 0xc0 {
+		return nil, fmt.Errorf("Unsupported transaction type %d", raw[0])
+	}
+
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Encodes signed transaction as accepted by eth_sendRawTransaction
+func EncodeTransaction(tx Transaction) ([]byte, error) {
+	if dynamic, ok := tx.(*DynamicFeeTx); ok {
+		return dynamic.MarshalBinary()
+	}
+
+	return rlp.EncodeToBytes(tx)
+}
+
+func (tx *DynamicFeeTx) ChainID() *big.Int         { return new(big.Int).Set(tx.data.ChainID) }
+func (tx *DynamicFeeTx) Nonce() uint64             { return tx.data.Nonce }
+func (tx *DynamicFeeTx) GasTipCap() *big.Int       { return new(big.Int).Set(tx.data.GasTipCap) }
+func (tx *DynamicFeeTx) GasFeeCap() *big.Int       { return new(big.Int).Set(tx.data.GasFeeCap) }
+func (tx *DynamicFeeTx) GasPrice() *big.Int        { return tx.GasFeeCap() }
+func (tx *DynamicFeeTx) Gas() uint64               { return tx.data.Gas }
+func (tx *DynamicFeeTx) Value() *big.Int           { return new(big.Int).Set(tx.data.Value) }
+func (tx *DynamicFeeTx) Data() []byte              { return common.CopyBytes(tx.data.Data) }
+func (tx *DynamicFeeTx) AccessList() []AccessTuple { return tx.data.AccessList }
+
+// Returns V (0 or 1), R and S of signature
+func (tx *DynamicFeeTx) RawSignatureValues() (*big.Int, *big.Int, *big.Int) {
+	return tx.data.V, tx.data.R, tx.data.S
+}
+
+func (tx *DynamicFeeTx) To() *common.Address {
+	if tx.data.To == nil {
+		return nil
+	}
+
+	to := *tx.data.To
+	return &to
+}
+
+// Hash of transaction envelope (transaction ID)
+func (tx *DynamicFeeTx) Hash() common.Hash {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return common.Hash{}
+	}
+
+	return crypto.Keccak256Hash(raw)
+}
+
+// Hash to be signed by sender
+func (tx *DynamicFeeTx) SigningHash() common.Hash {
+	d := tx.data
+	payload, _ := rlp.EncodeToBytes(dynamicFeeTxUnsigned{d.ChainID, d.Nonce, d.GasTipCap, d.GasFeeCap, d.Gas, d.To, d.Value, d.Data, d.AccessList})
+	return crypto.Keccak256Hash([]byte{DynamicFeeTxType}, payload)
+}
+
+// Encodes transaction envelope, as accepted by eth_sendRawTransaction
+func (tx *DynamicFeeTx) MarshalBinary() ([]byte, error) {
+	payload, err := rlp.EncodeToBytes(&tx.data)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{DynamicFeeTxType}, payload...), nil
+}
+
+// Returns copy of transaction with 65-byte [R || S || V] signature, V is 0 or 1
+func (tx *DynamicFeeTx) WithSignature(sig []byte) (*DynamicFeeTx, error) {
+	if len(sig) != 65 || sig[64] > 1 {
+		return nil, errors.New("Invalid transaction signature")
+	}
+
+	signed := &DynamicFeeTx{data: tx.data}
+	signed.data.R = new(big.Int).SetBytes(sig[:32])
+	signed.data.S = new(big.Int).SetBytes(sig[32:64])
+	signed.data.V = new(big.Int).SetUint64(uint64(sig[64]))
+	return signed, nil
+}
+
+func (tx *DynamicFeeTx) Sign(key *ecdsa.PrivateKey) (*DynamicFeeTx, error) {
+	hash := tx.SigningHash()
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.WithSignature(sig)
+}
+
+// Recovers sender from signature
+func (tx *DynamicFeeTx) Sender() (common.Address, error) {
+	d := tx.data
+	if d.V == nil || d.R == nil || d.S == nil || d.V.BitLen() > 1 {
+		return common.Address{}, errors.New("Invalid transaction signature")
+	}
+
+	v := byte(d.V.Uint64())
+	if !crypto.ValidateSignatureValues(v, d.R, d.S, true) {
+		return common.Address{}, errors.New("Invalid transaction signature")
+	}
+
+	sig := make([]byte, 65)
+	copy(sig[32-len(d.R.Bytes()):32], d.R.Bytes())
+	copy(sig[64-len(d.S.Bytes()):64], d.S.Bytes())
+	sig[64] = v
+
+	hash := tx.SigningHash()
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signed transaction of TestEIP1559BlockEncoding (go-ethereum core/types), hashes
+// and sender as computed by go-ethereum v1.13.15
+var blockVector = struct {
+	raw, hash, signingHash, sender string
+}{
+	raw:         "02f8a0018080843b9aca008301e24194095e7baea6a6c7c4c2dfeb977efac326af552d878080f838f7940000000000000000000000000000000000000001e1a0000000000000000000000000000000000000000000000000000000000000000080a0fe38ca4e44a30002ac54af7cf922a6ac2ba11b7d22f548e8ecb3f51f41cb31b0a06de6a5cbae13c0c856e33acf021b51819636cfc009d39eafb9f606d546e305a8",
+	hash:        "0xc5a8f6026a3554e9731e6ad1c17a7450b8fe2d048cd755752cc985a89a2e125c",
+	signingHash: "0xcdb92fd0725cbeabdff219fcff9c7682b55df80adf1d0a0944148d615fbbf498",
+	sender:      "0xa8E20d02Fb65adAa95f9279B325D8092724C81ee",
+}
+
+// Transaction signed by go-ethereum v1.13.15 with its test key
+var signedVector = struct {
+	key, raw, hash, signingHash, sender string
+}{
+	key:         "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291",
+	raw:         "02f87305078459682f008506fc23ac0082520894095e7baea6a6c7c4c2dfeb977efac326af552d87880de0b6b3a764000080c001a0213c3e5ac58bb6add189d8105c94a78066b8b1a5ec0f397a11092d61e8d8474ca03c9662df79959af68e569a3ebb0724ed792866eb8b07bbfad69801dfe680a62b",
+	hash:        "0x0def3a5a29c0a50602453a569b55a22b099d0bd9cc5bdb312265c57655dc5d91",
+	signingHash: "0xe563c44f2fe8ad9ae7281692581fcedbf2cf686dd92efb55bed79f93743df58f",
+	sender:      "0x71562b71999873DB5b286dF957af199Ec94617F7",
+}
+
+func TestDecodeDynamicFeeTx(t *testing.T) {
+	raw := common.FromHex(blockVector.raw)
+	decoded, err := DecodeTransaction(raw)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	tx, ok := decoded.(*DynamicFeeTx)
+	if !ok {
+		t.Fatalf("decoded %T, want *DynamicFeeTx", decoded)
+	}
+
+	to := common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	switch {
+	case tx.ChainID().Cmp(big.NewInt(1)) != 0:
+		t.Errorf("chain ID %s, want 1", tx.ChainID())
+	case tx.Nonce() != 0:
+		t.Errorf("nonce %d, want 0", tx.Nonce())
+	case tx.GasTipCap().Sign() != 0:
+		t.Errorf("tip cap %s, want 0", tx.GasTipCap())
+	case tx.GasFeeCap().Cmp(big.NewInt(1000000000)) != 0:
+		t.Errorf("fee cap %s, want 1000000000", tx.GasFeeCap())
+	case tx.Gas() != 123457:
+		t.Errorf("gas %d, want 123457", tx.Gas())
+	case tx.To() == nil || *tx.To() != to:
+		t.Errorf("to %v, want %s", tx.To(), to.String())
+	case tx.Value().Sign() != 0:
+		t.Errorf("value %s, want 0", tx.Value())
+	case len(tx.AccessList()) != 1 || tx.AccessList()[0].Address != common.HexToAddress("0x01") || len(tx.AccessList()[0].StorageKeys) != 1:
+		t.Errorf("access list %v", tx.AccessList())
+	}
+
+	encoded, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if !bytes.Equal(encoded, raw) {
+		t.Errorf("encoding mismatch:\ngot:  %x\nwant: %x", encoded, raw)
+	}
+
+	checkDynamicFeeTx(t, tx, blockVector.hash, blockVector.signingHash, blockVector.sender)
+}
+
+func TestSignDynamicFeeTx(t *testing.T) {
+	key, err := crypto.HexToECDSA(signedVector.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to := common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	value, _ := new(big.Int).SetString("1000000000000000000", 10)
+	tx := NewDynamicFeeTx(big.NewInt(5), 7, to, value, 21000, big.NewInt(1500000000), big.NewInt(30000000000), nil)
+	signed, err := tx.Sign(key)
+	if err != nil {
+		t.Fatalf("sign error: %v", err)
+	}
+
+	raw, err := EncodeTransaction(signed)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+
+	if want := common.FromHex(signedVector.raw); !bytes.Equal(raw, want) {
+		t.Errorf("encoding mismatch:\ngot:  %x\nwant: %x", raw, want)
+	}
+
+	checkDynamicFeeTx(t, signed, signedVector.hash, signedVector.signingHash, signedVector.sender)
+}
+
+func TestDecodeTransactionRejectsUnknownType(t *testing.T) {
+	if _, err := DecodeTransaction([]byte{0x03, 0xc0}); err == nil {
+		t.Error("expected error for unsupported transaction type")
+	}
+}
+
+func checkDynamicFeeTx(t *testing.T, tx *DynamicFeeTx, hash, signingHash, sender string) {
+	t.Helper()
+	if got := tx.Hash().Hex(); got != hash {
+		t.Errorf("hash %s, want %s", got, hash)
+	}
+
+	if got := tx.SigningHash().Hex(); got != signingHash {
+		t.Errorf("signing hash %s, want %s", got, signingHash)
+	}
+
+	from, err := tx.Sender()
+	if err != nil {
+		t.Fatalf("sender error: %v", err)
+	}
+
+	if from != common.HexToAddress(sender) {
+		t.Errorf("sender %s, want %s", from.String(), sender)
+	}
+}
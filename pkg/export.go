@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var Formats = []string{"table", "csv", "json"}
+
+type AddressInfo struct {
+	Index     uint32 `json:"index"`
+	Path      string `json:"path"`
+	Address   string `json:"address"`   // EIP-55 checksummed
+	PublicKey string `json:"publicKey"` // Compressed, hex-encoded
+}
+
+func NewAddressInfo(keychain *Keychain, index uint32, key *btcec.PublicKey) AddressInfo {
+	return AddressInfo{
+		Index:     index,
+		Path:      keychain.DerivationPath(index),
+		Address:   crypto.PubkeyToAddress(*key.ToECDSA()).Hex(),
+		PublicKey: hex.EncodeToString(key.SerializeCompressed()),
+	}
+}
+
+func IsValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+func WriteAddresses(w io.Writer, format string, list []AddressInfo) error {
+	switch format {
+	case "table":
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(table, "INDEX\tPATH\tADDRESS\tPUBLIC KEY\n")
+		for _, info := range list {
+			fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", info.Index, info.Path, info.Address, info.PublicKey)
+		}
+		return table.Flush()
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"index", "path", "address", "public_key"})
+		for _, info := range list {
+			index := strconv.FormatUint(uint64(info.Index), 10)
+			writer.Write([]string{index, info.Path, info.Address, info.PublicKey})
+		}
+		writer.Flush()
+		return writer.Error()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	}
+
+	return fmt.Errorf("Unknown output format %q", format)
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// Recent blocks to suggest priority fee from
+	FeeHistoryBlocks = 20
+
+	// Percentile of priority fees paid in each block
+	FeeHistoryPercentile = 50
+)
+
+// Subset of eth_feeHistory result
+type rpcFeeHistory struct {
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas"` // Includes next block
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+	Reward       [][]*hexutil.Big `json:"reward"`
+}
+
+// Returns base fee of next block and priority fee suggested as median of
+// recent blocks' priority fees (at FeeHistoryPercentile), empty blocks are skipped
+func (m *Manager) SuggestDynamicFee() (*big.Int, *big.Int, error) {
+	var history rpcFeeHistory
+	percentiles := []float64{FeeHistoryPercentile}
+	if err := m.RPC.CallContext(m.Context, &history, "eth_feeHistory", hexutil.Uint64(FeeHistoryBlocks), "latest", percentiles); err != nil {
+		return nil, nil, fmt.Errorf("Could not fetch fee history: %s", err.Error())
+	}
+
+	if len(history.BaseFee) == 0 || history.BaseFee[len(history.BaseFee)-1].ToInt().Sign() <= 0 {
+		return nil, nil, errors.New("Network doesn't support dynamic fee (EIP-1559) transactions")
+	}
+
+	baseFee := history.BaseFee[len(history.BaseFee)-1].ToInt()
+	rewards := []*big.Int{}
+	for i, reward := range history.Reward {
+		if len(reward) == 0 || reward[0] == nil || (i < len(history.GasUsedRatio) && history.GasUsedRatio[i] == 0) {
+			continue
+		}
+
+		rewards = append(rewards, reward[0].ToInt())
+	}
+
+	if len(rewards) == 0 {
+		var tip hexutil.Big
+		if err := m.RPC.CallContext(m.Context, &tip, "eth_maxPriorityFeePerGas"); err != nil {
+			return nil, nil, err
+		}
+
+		return baseFee, tip.ToInt(), nil
+	}
+
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+	return baseFee, rewards[len(rewards)/2], nil
+}
+
+// Sets priority fee and max fee per gas of dynamic fee transactions, missing
+// ones are suggested. Suggested max fee covers base fee doubling (six full
+// blocks in a row), so transactions stay valid while base fee rises
+func (m *Manager) SetDynamicFee() error {
+	fmt.Printf("Fetching fee history...\n")
+	baseFee, tip, err := m.SuggestDynamicFee()
+	if err != nil {
+		return err
+	}
+
+	if m.GasTipCap == nil {
+		m.GasTipCap = tip
+	}
+
+	if m.GasPrice.Cmp(BigZero) != 1 {
+		m.GasPrice = new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), m.GasTipCap)
+	}
+
+	if m.GasPrice.Cmp(m.GasTipCap) < 0 {
+		return errors.New("Max fee should not be lower than priority fee")
+	}
+
+	// Expected price is checked, max fee is only paid if base fee rises
+	if err := m.checkGasPrice(new(big.Int).Add(baseFee, m.GasTipCap)); err != nil {
+		return err
+	}
+
+	if m.GasPrice.Cmp(baseFee) < 0 {
+		fmt.Printf("Warning: max fee is below current base fee, transactions will wait until it drops\n")
+	}
+
+	// Reserve maximum possible fee, actual one is reported from receipts
+	m.GasCost = new(big.Int).Mul(m.GasPrice, m.GasLimit)
+
+	units := Units(m.Wei)
+	gasCost := WeiOrEther(m.GasCost, m.Wei)
+	fmt.Printf("Base fee: %s gwei, priority fee: %s gwei, max fee: %s gwei\n", GweiString(baseFee), GweiString(m.GasTipCap), GweiString(m.GasPrice))
+	fmt.Printf("Max gas cost per tx: %s %s\n", gasCost.String(), units)
+	return nil
+}
@@ -0,0 +1,57 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// Gas of plain transfer to externally owned account
+	TransferGas = 21000
+
+	// Estimates above TransferGas are multiplied by it, as contract execution
+	// may cost more when mined than when estimated
+	DefaultGasMultiplier = 1.2
+)
+
+// Estimates gas limit of transfer with eth_estimateGas. Failed estimation
+// falls back to TransferGas for externally owned accounts only, transfer
+// to contract would run out of gas
+func (m *Manager) EstimateGas(from, to common.Address, value *big.Int) (uint64, error) {
+	msg := ethereum.CallMsg{From: from, To: &to, Value: value}
+	gas, err := m.Client.EstimateGas(m.Context, msg)
+	if err != nil {
+		code, codeErr := m.Client.CodeAt(m.Context, to, nil)
+		if codeErr != nil {
+			return 0, codeErr
+		}
+
+		if len(code) > 0 {
+			return 0, fmt.Errorf("Could not estimate gas of transfer to contract %s: %s", to.String(), err.Error())
+		}
+
+		return TransferGas, nil
+	}
+
+	if gas <= TransferGas {
+		return gas, nil
+	}
+
+	multiplier := m.GasMultiplier
+	if multiplier == 0 {
+		multiplier = DefaultGasMultiplier
+	}
+
+	limit := uint64(math.Ceil(float64(gas) * multiplier))
+	fmt.Printf("Estimated gas of transfer from %s to %s: %d, using %d\n", from.String(), to.String(), gas, limit)
+	return limit, nil
+}
+
+// Maximum fee of transaction with given gas limit
+func (m *Manager) GasFee(gas uint64) *big.Int {
+	return new(big.Int).Mul(m.GasPrice, new(big.Int).SetUint64(gas))
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+type KeyType int
+
+const (
+	KeyAny KeyType = iota
+	KeyPublic
+	KeyPrivate
+)
+
+type KeychainInput struct {
+	Key            string  // Serialized extended key
+	KeyType        KeyType // Required type of serialized extended key
+	MnemonicFile   string  // BIP-39 mnemonic source (see ReadSecret)
+	PassphraseFile string  // BIP-39 passphrase source (optional)
+	Path           string  // Derivation path template
+	Account        uint32  // Value of {account} in path
+	Internal       bool    // Use internal (change) chain as {chain} in path
+	Insecure       bool    // Allow private keys as literal command line values
+	KeyName        string  // Name of vault entry holding extended key or mnemonic
+	VaultPath      string  // Vault file (see OpenVault)
+	VaultPassword  string  // Vault password source (prompt if empty)
+}
+
+func (in KeychainInput) IsEmpty() bool {
+	return len(in.Key) == 0 && len(in.MnemonicFile) == 0 && len(in.KeyName) == 0
+}
+
+func (in KeychainInput) DerivationPath() (Path, error) {
+	template := in.Path
+	if len(template) == 0 {
+		template = DefaultPath
+	}
+
+	chain := uint32(ExternalChain)
+	if in.Internal {
+		chain = InternalChain
+	}
+
+	return ParsePath(template, in.Account, chain)
+}
+
+func (in KeychainInput) Keychain() (*Keychain, error) {
+	path, err := in.DerivationPath()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := in.ExtendedKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromKey(key, path)
+}
+
+// Returns supplied extended key or master key derived from mnemonic
+func (in KeychainInput) ExtendedKey() (*hdkeychain.ExtendedKey, error) {
+	if len(in.KeyName) > 0 {
+		if len(in.Key) > 0 || len(in.MnemonicFile) > 0 {
+			return nil, errors.New("Vault key name, extended key and mnemonic are mutually exclusive")
+		}
+
+		entry, err := UnlockVaultEntry(in.VaultPath, in.VaultPassword, in.KeyName)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := entry.ExtendedKey()
+		if err != nil {
+			return nil, err
+		}
+
+		return key, in.checkKeyType(key)
+	}
+
+	if len(in.MnemonicFile) == 0 {
+		// Public keys are not secret, so they are fine on command line
+		raw, err := ReadKey(in.Key, "Enter extended key: ", in.Insecure || in.KeyType == KeyPublic)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := hdkeychain.NewKeyFromString(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return key, in.checkKeyType(key)
+	}
+
+	if len(in.Key) > 0 {
+		return nil, errors.New("Extended key and mnemonic are mutually exclusive")
+	}
+
+	mnemonic, err := ReadSecret(in.MnemonicFile, "Enter mnemonic: ")
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase := ""
+	if len(in.PassphraseFile) > 0 {
+		passphrase, err = ReadSecret(in.PassphraseFile, "Enter passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return MasterFromMnemonic(mnemonic, passphrase)
+}
+
+func (in KeychainInput) checkKeyType(key *hdkeychain.ExtendedKey) error {
+	if in.KeyType == KeyPublic && key.IsPrivate() {
+		return errors.New("Expected extended public key (xpub), but got extended private key")
+	}
+
+	if in.KeyType == KeyPrivate && !key.IsPrivate() {
+		return errors.New("Expected extended private key (xprv), but got extended public key")
+	}
+
+	return nil
+}
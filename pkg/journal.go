@@ -0,0 +1,294 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	JournalVersion = 1
+
+	// Transaction states, in order of progress
+	JournalPlanned   = "planned"
+	JournalSigned    = "signed"
+	JournalBroadcast = "broadcast" // recorded before sending, so may be not sent
+	JournalMined     = "mined"
+	JournalFailed    = "failed"   // mined, but reverted
+	JournalReplaced  = "replaced" // nonce used by another transaction
+)
+
+// Bundle with state of every transaction, saved on each change
+type Journal struct {
+	Version int            `json:"version"`
+	Updated time.Time      `json:"updated"`
+	Bundle  *Bundle        `json:"bundle"`
+	States  []JournalState `json:"states"` // Per bundle transaction
+	path    string
+}
+
+type JournalState struct {
+	State       string    `json:"state"`
+	Updated     time.Time `json:"updated"`
+	BlockNumber uint64    `json:"blockNumber,omitempty"`
+}
+
+// Journal directory (~/.ethereum-hd-tools/journal)
+func DefaultJournalDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "journal"
+	}
+
+	return filepath.Join(home, ".ethereum-hd-tools", "journal")
+}
+
+// Journal file for bundle in given directory (default directory if empty)
+func JournalPath(dir string, bundle *Bundle) string {
+	if len(dir) == 0 {
+		dir = DefaultJournalDir()
+	}
+
+	name := fmt.Sprintf("%s-%s.json", bundle.Kind, bundle.Created.Format("20060102T150405.000Z"))
+	return filepath.Join(dir, name)
+}
+
+func NewJournal(path string, bundle *Bundle) (*Journal, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("Journal %s already exists", path)
+	}
+
+	journal := &Journal{Version: JournalVersion, Bundle: bundle, path: path}
+	for range bundle.Transactions {
+		journal.States = append(journal.States, JournalState{State: JournalPlanned})
+	}
+
+	if err := journal.Update(JournalPlanned); err != nil {
+		return nil, err
+	}
+
+	return journal, nil
+}
+
+func OpenJournal(path string) (*Journal, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &Journal{path: path}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, err
+	}
+
+	if journal.Version != JournalVersion {
+		return nil, fmt.Errorf("Unsupported journal version %d", journal.Version)
+	}
+
+	if journal.Bundle == nil || len(journal.States) != len(journal.Bundle.Transactions) {
+		return nil, errors.New("Journal is corrupted")
+	}
+
+	if err := journal.Bundle.Validate(); err != nil {
+		return nil, err
+	}
+
+	return journal, nil
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Sets state of all transactions and saves journal
+func (j *Journal) Update(state string) error {
+	for i := range j.States {
+		j.States[i] = JournalState{State: state, Updated: time.Now().UTC()}
+	}
+
+	return j.Save()
+}
+
+// Sets state of single transaction and saves journal
+func (j *Journal) UpdateTx(i int, state string, blockNumber uint64) error {
+	j.States[i] = JournalState{State: state, Updated: time.Now().UTC(), BlockNumber: blockNumber}
+	return j.Save()
+}
+
+// Final states need no further action
+func (j *Journal) Done(i int) bool {
+	switch j.States[i].State {
+	case JournalMined, JournalFailed, JournalReplaced:
+		return true
+	}
+
+	return false
+}
+
+// Writes journal atomically and syncs it to disk
+func (j *Journal) Save() error {
+	j.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := j.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, j.path)
+}
+
+func (j *Journal) Print() {
+	counts := map[string]int{}
+	for _, state := range j.States {
+		counts[state.State]++
+	}
+
+	fmt.Printf("Journal %s: %d transactions", j.path, len(j.States))
+	for _, state := range []string{JournalPlanned, JournalSigned, JournalBroadcast, JournalMined, JournalFailed, JournalReplaced} {
+		if counts[state] > 0 {
+			fmt.Printf(", %d %s", counts[state], state)
+		}
+	}
+	fmt.Println()
+}
+
+// Records speed up in journal, so resume tracks replacement transaction
+func (j *Journal) ReplaceTx(i int, signed Transaction) error {
+	raw, err := EncodeTransaction(signed)
+	if err != nil {
+		return err
+	}
+
+	hash := signed.Hash()
+	tx := &j.Bundle.Transactions[i]
+	tx.GasPrice = signed.GasPrice()
+	if dynamic, ok := signed.(*DynamicFeeTx); ok {
+		tx.GasTipCap = dynamic.GasTipCap()
+	}
+
+	tx.Raw = raw
+	tx.Hash = &hash
+	return j.UpdateTx(i, JournalBroadcast, 0)
+}
+
+// Reconciles journal with chain state and sends only outstanding transactions.
+// Signed transactions keep their nonces, so sending them again can't pay twice:
+// mined ones are recorded, ones with nonce used by another transaction are
+// marked replaced and ones known to node are only waited for
+func (m *Manager) Resume(journal *Journal, signer Signer) (int, *big.Int, error) {
+	m.journal = journal
+	bundle := journal.Bundle
+
+	// Interrupted before signing
+	if !bundle.Signed() {
+		if signer == nil {
+			return 0, BigZero, errors.New("Journal transactions are not signed, please provide private key")
+		}
+
+		if err := bundle.Sign(signer); err != nil {
+			return 0, BigZero, err
+		}
+
+		if err := journal.Update(JournalSigned); err != nil {
+			return 0, BigZero, err
+		}
+	}
+
+	head, err := m.BlockNumber()
+	if err != nil {
+		return 0, BigZero, err
+	}
+
+	outstanding := []int{}
+	pending := []int{}
+	for i, tx := range bundle.Transactions {
+		if journal.Done(i) {
+			continue
+		}
+
+		// Nonce is fetched first, so transaction mined in between has receipt
+		nonce, err := m.Client.NonceAt(m.Context, tx.From, nil)
+		if err != nil {
+			return 0, BigZero, err
+		}
+
+		receipt, err := m.TransactionReceipt(*tx.Hash, head)
+		if err != nil {
+			return 0, BigZero, err
+		}
+
+		if receipt.Status != TxPending {
+			state := JournalMined
+			if receipt.Status == TxFailed {
+				state = JournalFailed
+			}
+
+			fmt.Printf("Transaction %s %s in block %d\n", tx.Hash.String(), receipt.Status, receipt.BlockNumber)
+			if err := journal.UpdateTx(i, state, receipt.BlockNumber); err != nil {
+				return 0, BigZero, err
+			}
+			continue
+		}
+
+		if nonce > tx.Nonce {
+			fmt.Printf("Transaction %s replaced, nonce %d of %s is used by another transaction\n", tx.Hash.String(), tx.Nonce, tx.From.String())
+			if err := journal.UpdateTx(i, JournalReplaced, 0); err != nil {
+				return 0, BigZero, err
+			}
+			continue
+		}
+
+		known, err := m.transactionByHash(*tx.Hash)
+		if err != nil {
+			return 0, BigZero, err
+		}
+
+		if known != nil && known.BlockNumber == nil {
+			fmt.Printf("Transaction %s is pending\n", tx.Hash.String())
+			if err := journal.UpdateTx(i, JournalBroadcast, 0); err != nil {
+				return 0, BigZero, err
+			}
+
+			pending = append(pending, i)
+			continue
+		}
+
+		outstanding = append(outstanding, i)
+	}
+
+	fmt.Printf("Outstanding transactions: %d of %d\n", len(outstanding), len(bundle.Transactions))
+	total, value, err := m.sendTxs(bundle, outstanding)
+	if waitErr := m.waitTxs(bundle, append(pending, outstanding[:total]...)); err == nil {
+		err = waitErr
+	}
+
+	return total, value, err
+}
@@ -1,43 +1,123 @@
 package pkg
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/tyler-smith/go-bip39"
 )
 
 type Keychain struct {
-	AccountKey  *hdkeychain.ExtendedKey
-	ExtChainKey *hdkeychain.ExtendedKey
+	AccountKey *hdkeychain.ExtendedKey // Supplied key (master or account level)
+	BaseKey    *hdkeychain.ExtendedKey // Parent key of {index} segment
+	Path       Path
+	cache      *keyCache
 }
 
-func New(xpub string) (*Keychain, error) {
+func New(xpub string, path Path) (*Keychain, error) {
 	account, err := hdkeychain.NewKeyFromString(xpub)
 	if err != nil {
 		return nil, err
 	}
 
-	external, err := account.Child(0)
+	return NewFromKey(account, path)
+}
+
+func NewFromMnemonic(mnemonic, passphrase string, path Path) (*Keychain, error) {
+	master, err := MasterFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromKey(master, path)
+}
+
+func MasterFromMnemonic(mnemonic, passphrase string) (*hdkeychain.ExtendedKey, error) {
+	// Normalize whitespace, so that words may be separated by newlines
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
 	if err != nil {
 		return nil, err
 	}
 
+	return hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+}
+
+// Key depth determines which path segments are already applied to it
+func NewFromKey(key *hdkeychain.ExtendedKey, path Path) (*Keychain, error) {
+	if err := ValidateKeyDepth(key, path); err != nil {
+		return nil, err
+	}
+
+	depth := int(key.Depth())
+	indexDepth := path.IndexDepth()
+	base, err := path.Derive(key, depth, indexDepth, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Memoize public key of base key upfront, as it is shared by
+	// concurrent derivations and hdkeychain computes it lazily
+	if _, err := base.ECPubKey(); err != nil {
+		return nil, err
+	}
+
 	k := new(Keychain)
-	k.AccountKey = account
-	k.ExtChainKey = external
+	k.AccountKey = key
+	k.BaseKey = base
+	k.Path = path
+	k.cache = newKeyCache(DefaultCacheSize)
 	return k, nil
 }
 
+// Ensures key is either master key or account level key of given path
+func ValidateKeyDepth(key *hdkeychain.ExtendedKey, path Path) error {
+	depth := int(key.Depth())
+	if depth == 0 {
+		return nil
+	}
+
+	accountDepth := path.AccountDepth()
+	if depth != accountDepth {
+		return fmt.Errorf("Extended key depth is %d, but path %s expects master key (depth 0) or account key (depth %d)", depth, path.String(), accountDepth)
+	}
+
+	// Check that account key is the same child, as specified in path
+	info, err := InspectKey(key)
+	if err != nil {
+		return err
+	}
+
+	expected, err := path.ChildNumber(depth-1, 0)
+	if err != nil {
+		return err
+	}
+
+	if info.ChildNumber != expected {
+		return fmt.Errorf("Extended key is child %s, but path %s expects child %s at depth %d", formatChild(info.ChildNumber), path.String(), formatChild(expected), depth)
+	}
+
+	return nil
+}
+
+func (k *Keychain) DeriveKey(index uint32) (*hdkeychain.ExtendedKey, error) {
+	return k.Path.Derive(k.BaseKey, k.Path.IndexDepth(), len(k.Path), index)
+}
+
 func (k *Keychain) DerivePublic(index uint32) (*btcec.PublicKey, error) {
-	child, err := k.ExtChainKey.Child(index)
+	derived, err := k.DeriveAddress(index)
 	if err != nil {
 		return nil, err
 	}
 
-	return child.ECPubKey()
+	return derived.Key, nil
 }
 
 func (k *Keychain) DerivePrivate(index uint32) (*btcec.PrivateKey, error) {
-	child, err := k.ExtChainKey.Child(index)
+	child, err := k.DeriveKey(index)
 	if err != nil {
 		return nil, err
 	}
@@ -45,27 +125,50 @@ func (k *Keychain) DerivePrivate(index uint32) (*btcec.PrivateKey, error) {
 	return child.ECPrivKey()
 }
 
+func (k *Keychain) DerivationPath(index uint32) string {
+	return k.Path.Format(index)
+}
+
 func (k *Keychain) DeriveMultiPublic(accounts []uint32) ([]*btcec.PublicKey, error) {
-	keys := []*btcec.PublicKey{}
-	for _, account := range accounts {
-		key, err := k.DerivePublic(account)
-		if err != nil {
-			return keys, err
-		}
-		keys = append(keys, key)
+	keys := make([]*btcec.PublicKey, len(accounts))
+	err := parallel(len(accounts), func(i int) error {
+		key, err := k.DerivePublic(accounts[i])
+		keys[i] = key
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (k *Keychain) DeriveMultiAddress(accounts []uint32) ([]DerivedKey, error) {
+	keys := make([]DerivedKey, len(accounts))
+	err := parallel(len(accounts), func(i int) error {
+		key, err := k.DeriveAddress(accounts[i])
+		keys[i] = key
+		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	return keys, nil
 }
 
 func (k *Keychain) DeriveMultiPrivate(accounts []uint32) ([]*btcec.PrivateKey, error) {
-	keys := []*btcec.PrivateKey{}
-	for _, account := range accounts {
-		key, err := k.DerivePrivate(account)
-		if err != nil {
-			return keys, err
-		}
-		keys = append(keys, key)
+	keys := make([]*btcec.PrivateKey, len(accounts))
+	err := parallel(len(accounts), func(i int) error {
+		key, err := k.DerivePrivate(accounts[i])
+		keys[i] = key
+		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
 	return keys, nil
@@ -0,0 +1,227 @@
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Standard scrypt parameters (as used by geth)
+	KeystoreScryptN = 1 << 18
+	KeystoreScryptP = 1
+
+	// Light scrypt parameters, faster to decrypt
+	KeystoreLightScryptN = 1 << 12
+	KeystoreLightScryptP = 6
+
+	// Limits of KDF parameters read from keystore files (crafted ones could exhaust memory or CPU)
+	maxKeystoreScryptN = 1 << 20
+	maxKeystoreScryptR = 8
+	maxKeystoreScryptP = 16
+	maxKeystorePBKDF2C = 1 << 22
+)
+
+// Web3 Secret Storage (V3 keystore) format
+type keystoreJSON struct {
+	Address string         `json:"address"`
+	Crypto  keystoreCrypto `json:"crypto"`
+	ID      string         `json:"id"`
+	Version int            `json:"version"`
+}
+
+type keystoreCrypto struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams keystoreCipherParams   `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type keystoreCipherParams struct {
+	IV string `json:"iv"`
+}
+
+func DecryptKeystore(data []byte, password string) ([]byte, error) {
+	keystore := keystoreJSON{}
+	if err := json.Unmarshal(data, &keystore); err != nil {
+		return nil, err
+	}
+
+	if keystore.Version != 3 {
+		return nil, fmt.Errorf("Unsupported keystore version %d", keystore.Version)
+	}
+
+	if keystore.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("Unsupported keystore cipher %s", keystore.Crypto.Cipher)
+	}
+
+	derivedKey, err := keystoreDerivedKey(keystore.Crypto, password)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherText, err := hex.DecodeString(keystore.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := hex.DecodeString(keystore.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+
+	calculated := crypto.Keccak256(derivedKey[16:32], cipherText)
+	if subtle.ConstantTimeCompare(calculated, mac) != 1 {
+		return nil, errors.New("Could not decrypt keystore with given password")
+	}
+
+	iv, err := hex.DecodeString(keystore.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("Invalid keystore IV")
+	}
+
+	return aesCTR(derivedKey[:16], iv, cipherText)
+}
+
+// Encrypts private key into Web3 Secret Storage JSON (scrypt, aes-128-ctr)
+func EncryptKeystore(key *ecdsa.PrivateKey, password string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := scrypt.Key([]byte(password), salt, scryptN, 8, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	cipherText, err := aesCTR(derivedKey[:16], iv, math.PaddedBigBytes(key.D, 32))
+	if err != nil {
+		return nil, err
+	}
+
+	// Random (version 4) UUID
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	keystore := keystoreJSON{
+		Address: hex.EncodeToString(address[:]),
+		Crypto: keystoreCrypto{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: keystoreCipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          "scrypt",
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     8,
+				"p":     scryptP,
+				"dklen": 32,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(crypto.Keccak256(derivedKey[16:32], cipherText)),
+		},
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Version: 3,
+	}
+
+	return json.Marshal(keystore)
+}
+
+// Keystore file name as used by geth (UTC--<created>--<address>)
+func KeystoreFileName(address common.Address, created time.Time) string {
+	timestamp := created.UTC().Format("2006-01-02T15-04-05.000000000Z")
+	return fmt.Sprintf("UTC--%s--%s", timestamp, hex.EncodeToString(address[:]))
+}
+
+func keystoreDerivedKey(params keystoreCrypto, password string) ([]byte, error) {
+	salt, err := hex.DecodeString(keystoreParam(params.KDFParams, "salt"))
+	if err != nil {
+		return nil, err
+	}
+
+	dkLen := keystoreIntParam(params.KDFParams, "dklen")
+	if dkLen != 32 {
+		return nil, fmt.Errorf("Unsupported keystore derived key length %d", dkLen)
+	}
+
+	switch params.KDF {
+	case "scrypt":
+		n := keystoreIntParam(params.KDFParams, "n")
+		r := keystoreIntParam(params.KDFParams, "r")
+		p := keystoreIntParam(params.KDFParams, "p")
+		if n <= 1 || n > maxKeystoreScryptN || r < 1 || r > maxKeystoreScryptR || p < 1 || p > maxKeystoreScryptP {
+			return nil, fmt.Errorf("Unsupported keystore scrypt parameters n=%d r=%d p=%d", n, r, p)
+		}
+
+		return scrypt.Key([]byte(password), salt, n, r, p, dkLen)
+	case "pbkdf2":
+		if prf := keystoreParam(params.KDFParams, "prf"); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("Unsupported keystore PBKDF2 PRF %s", prf)
+		}
+
+		c := keystoreIntParam(params.KDFParams, "c")
+		if c < 1 || c > maxKeystorePBKDF2C {
+			return nil, fmt.Errorf("Unsupported keystore PBKDF2 iteration count %d", c)
+		}
+
+		return pbkdf2.Key([]byte(password), salt, c, dkLen, sha256.New), nil
+	}
+
+	return nil, fmt.Errorf("Unsupported keystore KDF %s", params.KDF)
+}
+
+func keystoreParam(params map[string]interface{}, name string) string {
+	value, _ := params[name].(string)
+	return value
+}
+
+// Returns -1 for missing, fractional or out of range values
+func keystoreIntParam(params map[string]interface{}, name string) int {
+	value, ok := params[name].(float64)
+	if !ok || value < 0 || value > 1<<31 || value != float64(int(value)) {
+		return -1
+	}
+
+	return int(value)
+}
+
+func aesCTR(key, iv, input []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, len(input))
+	cipher.NewCTR(block, iv).XORKeyStream(output, input)
+	return output, nil
+}
@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Number of indices processed by worker at once
+const locateBatchSize = 1000
+
+// Searches index range [from, until] for target addresses using parallel
+// derivation, stops as soon as all targets are found
+func (k *Keychain) Locate(targets []common.Address, from, until uint32, workers int) ([]AddressInfo, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	pending := map[common.Address]bool{}
+	for _, target := range targets {
+		pending[target] = true
+	}
+
+	var (
+		mutex   sync.Mutex
+		wg      sync.WaitGroup
+		next    = uint64(from)
+		scanned uint64
+		failure error
+		found   = []AddressInfo{}
+		total   = uint64(until) - uint64(from) + 1
+	)
+
+	done := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(pending) == 0 || failure != nil
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done() {
+				// Claim next batch of indices
+				start := atomic.AddUint64(&next, locateBatchSize) - locateBatchSize
+				if start > uint64(until) {
+					return
+				}
+
+				end := start + locateBatchSize - 1
+				if end > uint64(until) {
+					end = uint64(until)
+				}
+
+				for i := start; i <= end; i++ {
+					// Bypass cache, as swept ranges are usually huge
+					derived, err := k.derivePublic(uint32(i))
+					if err != nil {
+						mutex.Lock()
+						failure = err
+						mutex.Unlock()
+						return
+					}
+
+					mutex.Lock()
+					if pending[derived.Address] {
+						delete(pending, derived.Address)
+						found = append(found, NewAddressInfo(k, derived.Index, derived.Key))
+					}
+					mutex.Unlock()
+				}
+
+				count := atomic.AddUint64(&scanned, end-start+1)
+				fmt.Fprintf(os.Stderr, "Scanned %d of %d indices (%s)\r", count, total, k.Path.String())
+			}
+		}()
+	}
+
+	wg.Wait()
+	fmt.Fprintln(os.Stderr)
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Index < found[j].Index
+	})
+
+	return found, failure
+}
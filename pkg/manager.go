@@ -2,32 +2,54 @@ package pkg
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
 	"time"
 
-	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/hdkeychain"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Manager struct {
 	Wei      bool
 	ChainID  *big.Int
-	GasPrice *big.Int
+	GasPrice *big.Int // Max fee per gas for dynamic fee transactions
 	GasLimit *big.Int
-	GasCost  *big.Int
+	GasCost  *big.Int // Maximum fee of plain transfer
 	Context  context.Context
 	Client   *ethclient.Client
+	RPC      *rpc.Client
+	Nonces   *NonceManager
+	DryRun   string // Write signed transactions to file instead of sending
+
+	// Gas price of legacy transactions is chosen by strategy (node suggestion
+	// if nil) unless given, safety limits are not checked if nil
+	GasStrategy  GasPriceStrategy
+	MaxGasPrice  *big.Int // Abort if network gas price is higher
+	MaxTotalFees *big.Int // Abort if maximum fees of all transactions are higher
+
+	// Safety multiplier of gas estimates (DefaultGasMultiplier if zero)
+	GasMultiplier float64
+
+	// Dynamic fee (EIP-1559) transactions, tip is suggested if nil
+	DynamicFee bool
+	GasTipCap  *big.Int
+
+	// Receipt tracking (disabled if zero confirmations)
+	Confirmations uint64
+	WaitTimeout   time.Duration
+
+	JournalDir string // Journal is not kept if empty
+	journal    *Journal
 }
 
 func NewManager(url string, chainID, gasPrice uint64, wei bool) (*Manager, error) {
-	client, err := ethclient.Dial(url)
+	client, err := rpc.Dial(url)
 	if err != nil {
 		return nil, err
 	}
@@ -35,30 +57,56 @@ func NewManager(url string, chainID, gasPrice uint64, wei bool) (*Manager, error
 	m := new(Manager)
 	m.Wei = wei
 	m.Context = context.Background()
-	m.Client = client
+	m.RPC = client
+	m.Client = ethclient.NewClient(client)
+	m.Nonces = NewNonceManager(m.Context, m.Client)
 	m.ChainID = new(big.Int).SetUint64(chainID)
 
 	gasPriceBig := new(big.Int).SetUint64(gasPrice)
 	m.GasPrice = new(big.Int).Mul(gasPriceBig, BigGwei)
-	m.GasLimit = big.NewInt(21000)
+	m.GasLimit = big.NewInt(TransferGas)
 	m.GasCost = new(big.Int).Mul(m.GasPrice, m.GasLimit)
 	return m, nil
 }
 
-func (m *Manager) GetPrivateKey(input string) (*ecdsa.PrivateKey, error) {
-	key, err := crypto.HexToECDSA(input)
+// Returns node chain ID (eth_chainId, or network ID on older nodes)
+func (m *Manager) NodeChainID() (*big.Int, error) {
+	var raw hexutil.Big
+	if err := m.RPC.CallContext(m.Context, &raw, "eth_chainId"); err != nil {
+		return m.Client.NetworkID(m.Context)
+	}
+
+	return (*big.Int)(&raw), nil
+}
+
+// Compares chain ID with node
+func (m *Manager) CheckChainID() error {
+	chainID, err := m.NodeChainID()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if chainID.Cmp(m.ChainID) != 0 {
+		return fmt.Errorf("Node chain ID %s doesn't match %s", chainID.String(), m.ChainID.String())
 	}
 
-	return key, nil
+	return nil
 }
 
 func (m *Manager) SetGasPrice() error {
+	if m.DynamicFee {
+		return m.SetDynamicFee()
+	}
+
 	// Get gas price (if necessary)
 	if m.GasPrice.Cmp(BigZero) != 1 {
-		fmt.Printf("Fetching suggested gas price...\n")
-		gasPrice, err := m.Client.SuggestGasPrice(m.Context)
+		strategy := m.GasStrategy
+		if strategy == nil {
+			strategy = NodeStrategy{}
+		}
+
+		fmt.Printf("Fetching gas price (%s)...\n", strategy.String())
+		gasPrice, err := strategy.GasPrice(m)
 		if err != nil {
 			return err
 		}
@@ -71,122 +119,380 @@ func (m *Manager) SetGasPrice() error {
 		}
 	}
 
+	if err := m.checkGasPrice(m.GasPrice); err != nil {
+		return err
+	}
+
 	units := Units(m.Wei)
 	gasCost := WeiOrEther(m.GasCost, m.Wei)
+	fmt.Printf("Gas price: %s gwei\n", GweiString(m.GasPrice))
 	fmt.Printf("Gas cost per tx: %s %s\n", gasCost.String(), units)
 	return nil
 }
 
-func (m *Manager) Distribute(prv *ecdsa.PrivateKey, keys []*btcec.PublicKey, amount *big.Int, random bool) (int, error) {
-	// Get nonce
-	from := crypto.PubkeyToAddress(prv.PublicKey)
-	nonce, err := m.Client.PendingNonceAt(m.Context, from)
+func (m *Manager) checkGasPrice(gasPrice *big.Int) error {
+	if m.MaxGasPrice != nil && gasPrice.Cmp(m.MaxGasPrice) > 0 {
+		return fmt.Errorf("Gas price %s gwei exceeds maximum of %s gwei, aborting", GweiString(gasPrice), GweiString(m.MaxGasPrice))
+	}
+
+	return nil
+}
+
+// Checks maximum fees of all bundle transactions against limit
+func (m *Manager) CheckTotalFees(bundle *Bundle) error {
+	_, fees := bundle.Totals()
+	return m.checkTotalFees(fees)
+}
+
+func (m *Manager) checkTotalFees(fees *big.Int) error {
+	if m.MaxTotalFees != nil && fees.Cmp(m.MaxTotalFees) > 0 {
+		units := Units(m.Wei)
+		return fmt.Errorf("Total fees %s %s exceed maximum of %s %s, aborting", WeiOrEther(fees, m.Wei).String(), units, WeiOrEther(m.MaxTotalFees, m.Wei).String(), units)
+	}
+
+	return nil
+}
+
+func (m *Manager) Distribute(signer Signer, account Account, keychain *Keychain, recipients []DerivedKey, amount *big.Int, random bool) (int, error) {
+	fmt.Printf("From address: %s\n", account.Address.String())
+
+	// Plan txs
+	bundle, err := m.PlanDistribute(keychain, account.Address, recipients, amount, random)
 	if err != nil {
 		return 0, err
 	}
 
-	units := Units(m.Wei)
-	total := 0
-
-	fmt.Printf("From address: %s\n", from.String())
+	// Sign & send txs
+	total, _, err := m.execute(signer, bundle)
+	return total, err
+}
 
+func (m *Manager) PlanDistribute(keychain *Keychain, from common.Address, recipients []DerivedKey, amount *big.Int, random bool) (*Bundle, error) {
 	entropy := rand.NewSource(time.Now().UnixNano())
 	limit := new(big.Int).SetInt64(1000000000)
 
-	for _, key := range keys {
+	bundle := NewBundle(BundleDistribute, m.ChainID, keychain)
+	for _, recipient := range recipients {
 		value := amount
 		if random {
 			epsilon := new(big.Int).Rand(rand.New(entropy), limit)
-			value = value.Add(value, epsilon)
+			value = new(big.Int).Add(amount, epsilon)
 		}
 
-		// Print destination & value
-		to := crypto.PubkeyToAddress(*key.ToECDSA())
-		printVal := WeiOrEther(value, m.Wei)
-		fmt.Printf("Sending %s %s to %s\n", printVal, units, to.String())
+		gas, err := m.EstimateGas(from, recipient.Address, value)
+		if err != nil {
+			m.discardBundle(bundle)
+			return nil, err
+		}
 
-		// Sign tx
-		rawTx := types.NewTransaction(nonce, to, value, m.GasLimit.Uint64(), m.GasPrice, nil)
-		tx, err := types.SignTx(rawTx, types.NewEIP155Signer(m.ChainID), prv)
+		nonce, err := m.Nonces.Next(from)
 		if err != nil {
-			return total, err
+			m.discardBundle(bundle)
+			return nil, err
 		}
 
-		// Send tx
+		bundle.Transactions = append(bundle.Transactions, BundleTx{
+			Index:     recipient.Index,
+			Path:      keychain.DerivationPath(recipient.Index),
+			From:      from,
+			To:        recipient.Address,
+			Nonce:     nonce,
+			Value:     value,
+			Gas:       gas,
+			GasPrice:  m.GasPrice,
+			GasTipCap: m.GasTipCap,
+		})
+	}
+
+	if err := m.CheckTotalFees(bundle); err != nil {
+		m.discardBundle(bundle)
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// Signs planned bundle, sends it and waits for receipts (if enabled),
+// keeping journal (if enabled) up to date
+func (m *Manager) execute(signer Signer, bundle *Bundle) (int, *big.Int, error) {
+	if len(m.JournalDir) > 0 && len(m.DryRun) == 0 {
+		journal, err := NewJournal(JournalPath(m.JournalDir, bundle), bundle)
+		if err != nil {
+			return 0, BigZero, err
+		}
+
+		fmt.Printf("Journal: %s\n", journal.Path())
+		m.journal = journal
+	}
+
+	if err := bundle.Sign(signer); err != nil {
+		return 0, BigZero, err
+	}
+
+	if m.journal != nil {
+		if err := m.journal.Update(JournalSigned); err != nil {
+			return 0, BigZero, err
+		}
+	}
+
+	total, value, err := m.SendBundle(bundle)
+	if waitErr := m.WaitBundle(bundle, total); err == nil {
+		err = waitErr
+	}
+
+	return total, value, err
+}
+
+// Sends signed transactions, returns number of sent transactions and total sent value
+func (m *Manager) SendBundle(bundle *Bundle) (int, *big.Int, error) {
+	if len(m.DryRun) > 0 {
+		return m.writeBundle(bundle)
+	}
+
+	indexes := []int{}
+	for i := range bundle.Transactions {
+		indexes = append(indexes, i)
+	}
+
+	return m.sendTxs(bundle, indexes)
+}
+
+// Sends selected transactions of bundle, stops at first failure
+func (m *Manager) sendTxs(bundle *Bundle, indexes []int) (int, *big.Int, error) {
+	units := Units(m.Wei)
+	total := 0
+	value := new(big.Int)
+
+	for j, i := range indexes {
+		data := bundle.Transactions[i]
+		tx, err := data.SignedTransaction()
+		if err != nil {
+			return total, value, err
+		}
+
+		// Record before sending, resume reconciles it with chain state
+		if m.journal != nil {
+			if err := m.journal.UpdateTx(i, JournalBroadcast, 0); err != nil {
+				return total, value, err
+			}
+		}
+
+		// Print destination & value
+		printValue := WeiOrEther(data.Value, m.Wei)
+		fmt.Printf("Sending %s %s from %s to %s\n", printValue.String(), units, data.From.String(), data.To.String())
+
+		// Send tx, nonces of unsent txs are reused by next plan
 		fmt.Printf("Sending transaction %s\n", tx.Hash().String())
-		if err := m.Client.SendTransaction(m.Context, tx); err != nil {
-			return total, err
+		if err := m.SendRawTransaction(data.Raw); err != nil {
+			m.releaseNonces(bundle, indexes[j:])
+			return total, value, err
 		}
 
 		// Increase counters
-		nonce++
+		value.Add(value, data.Value)
 		total++
 	}
 
-	return total, nil
+	return total, value, nil
 }
 
-func (m *Manager) GetBalances(keychain *Keychain, from, until uint) (*Result, error) {
-	data := []TxData{}
-	total := BigZero
+// Submits signed transaction of any type (vendored client only encodes legacy ones)
+func (m *Manager) SendRawTransaction(raw hexutil.Bytes) error {
+	return m.RPC.CallContext(m.Context, nil, "eth_sendRawTransaction", raw)
+}
 
-	for i := from; i <= until; i = i + 1 {
-		accountID := uint32(i)
-		key, err := keychain.DerivePublic(accountID)
-		if err != nil {
-			return nil, err
+func (m *Manager) releaseNonces(bundle *Bundle, indexes []int) {
+	for _, i := range indexes {
+		tx := bundle.Transactions[i]
+		m.Nonces.Release(tx.From, tx.Nonce)
+	}
+}
+
+// Returns nonces of all planned transactions, so that aborted plan leaves no gap
+func (m *Manager) discardBundle(bundle *Bundle) {
+	for _, tx := range bundle.Transactions {
+		m.Nonces.Release(tx.From, tx.Nonce)
+	}
+}
+
+// Waits for receipts of first sent transactions of bundle (if enabled) and prints report
+func (m *Manager) WaitBundle(bundle *Bundle, sent int) error {
+	indexes := []int{}
+	for i := 0; i < sent; i++ {
+		indexes = append(indexes, i)
+	}
+
+	return m.waitTxs(bundle, indexes)
+}
+
+func (m *Manager) waitTxs(bundle *Bundle, indexes []int) error {
+	if m.Confirmations == 0 || len(m.DryRun) > 0 || len(indexes) == 0 {
+		return nil
+	}
+
+	hashes := []common.Hash{}
+	for _, i := range indexes {
+		hashes = append(hashes, *bundle.Transactions[i].Hash)
+	}
+
+	receipts, err := m.WaitReceipts(hashes, m.Confirmations, m.WaitTimeout)
+	if err != nil {
+		return err
+	}
+
+	for j, receipt := range receipts {
+		i := indexes[j]
+
+		// Older nodes don't report effective gas price (known upfront for legacy transactions only)
+		if receipt.GasPrice == nil && !bundle.Transactions[i].DynamicFee() {
+			receipts[j].GasPrice = bundle.Transactions[i].GasPrice
+		}
+
+		if m.journal != nil && receipt.Settled(m.Confirmations) {
+			state := JournalMined
+			if receipt.Status == TxFailed {
+				state = JournalFailed
+			}
+
+			if err := m.journal.UpdateTx(i, state, receipt.BlockNumber); err != nil {
+				return err
+			}
+		}
+	}
+
+	return PrintReceipts(receipts, m.Confirmations, m.Wei)
+}
+
+// Dry run, writes signed transactions to file (to be sent later by broadcast command)
+func (m *Manager) writeBundle(bundle *Bundle) (int, *big.Int, error) {
+	for _, data := range bundle.Transactions {
+		if _, err := data.SignedTransaction(); err != nil {
+			return 0, BigZero, err
 		}
 
-		address := crypto.PubkeyToAddress(*key.ToECDSA())
+		fmt.Printf("Signed transaction %s (not sent)\n", data.Hash.String())
+	}
+
+	bundle.Print(m.Wei)
+	if err := bundle.WriteFile(m.DryRun); err != nil {
+		return 0, BigZero, err
+	}
+
+	fmt.Printf("Dry run, signed bundle written to %s\n", m.DryRun)
+	value, _ := bundle.Totals()
+	return len(bundle.Transactions), value, nil
+}
+
+// Iterates over accounts in range [from, until] or, if until is zero, until
+// gap consecutive accounts have zero balance and zero nonce (BIP-44 gap limit).
+// Returns highest used account number (-1 if none)
+func (m *Manager) scanAccounts(keychain *Keychain, from, until, gap uint, visit func(TxData) error) (int, error) {
+	lastUsed := -1
+	unused := uint(0)
+
+	// Gap limit mode scans up to the last non-hardened index
+	last := uint32(hdkeychain.HardenedKeyStart - 1)
+	if until > 0 {
+		last = uint32(until)
+	}
+
+	it := keychain.Iterate(uint32(from), last)
+	defer it.Close()
+
+	for it.Next() {
+		if until == 0 && unused >= gap {
+			break
+		}
+
+		derived := it.Key()
+		i, address := derived.Index, derived.Address
 		fmt.Printf("Fetching balance for account %d (%s)\r", i, address.String())
 		balance, err := m.Client.BalanceAt(m.Context, address, nil)
 		if err != nil {
-			return nil, err
+			return lastUsed, err
 		}
 
-		// Update results
-		if balance.Uint64() > 0 {
-			total = new(big.Int).Add(total, balance)
-			data = append(data, TxData{
-				ID:      accountID,
-				Address: address,
-				Balance: balance,
-			})
+		// Nonce is only needed to detect used accounts in gap limit mode
+		nonce := uint64(0)
+		if until == 0 {
+			nonce, err = m.Client.NonceAt(m.Context, address, nil)
+			if err != nil {
+				return lastUsed, err
+			}
+		}
+
+		if balance.Cmp(BigZero) == 0 && nonce == 0 {
+			unused++
+		} else {
+			unused = 0
+			lastUsed = int(i)
 		}
+
+		data := TxData{ID: i, Address: address, Balance: balance, Nonce: nonce}
+		if err := visit(data); err != nil {
+			return lastUsed, err
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return lastUsed, err
 	}
 
 	fmt.Println()
-	result := &Result{Total: total, Data: data, GasCost: m.GasCost}
-	return result, nil
+	return lastUsed, nil
 }
 
-func (m *Manager) GetBalancesUntil(keychain *Keychain, amount *big.Int, from, until uint) (*Result, error) {
+func (m *Manager) GetBalances(keychain *Keychain, from, until, gap uint) (*Result, error) {
 	data := []TxData{}
 	total := BigZero
-	target := BigZero
 
-	for i := from; i <= until; i = i + 1 {
-		accountID := uint32(i)
-		key, err := keychain.DerivePublic(accountID)
-		if err != nil {
-			return nil, err
+	lastUsed, err := m.scanAccounts(keychain, from, until, gap, func(account TxData) error {
+		// Update results
+		if account.Balance.Uint64() > 0 {
+			total = new(big.Int).Add(total, account.Balance)
+			data = append(data, account)
 		}
 
-		address := crypto.PubkeyToAddress(*key.ToECDSA())
-		fmt.Printf("Fetching balance for account %d (%s)\r", i, address.String())
-		balance, err := m.Client.BalanceAt(m.Context, address, nil)
-		if err != nil {
-			return nil, err
-		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Total: total, Data: data, GasCost: m.GasCost, LastUsed: lastUsed}
+	return result, nil
+}
+
+// Selects accounts until amount is collected to given destination, every
+// account reserves maximum fee of its own estimated gas limit
+func (m *Manager) GetBalancesUntil(keychain *Keychain, amount *big.Int, to common.Address, from, until, gap uint) (*Result, error) {
+	data := []TxData{}
+	total := BigZero
+	target := BigZero
+	fees := new(big.Int)
+
+	lastUsed, err := m.scanAccounts(keychain, from, until, gap, func(account TxData) error {
+		balance := account.Balance
 
-		// Subtract fees (if not used by bookkeeper), skip if no funds
+		// Subtract fees (if not used by bookkeeper), skip if no funds or target is reached
 		delta := new(big.Int)
 		if amount != nil {
-			delta := delta.Sub(balance, m.GasCost)
+			if balance.Cmp(BigZero) <= 0 || target.Cmp(amount) >= 0 {
+				return nil
+			}
+
+			gas, err := m.EstimateGas(account.Address, to, balance)
+			if err != nil {
+				return err
+			}
+
+			account.Gas = gas
+			delta := delta.Sub(balance, m.GasFee(gas))
 			//fmt.Printf("Delta (balance - gas costs) == %s\n", delta.String())
 			if delta.Cmp(BigZero) <= 0 { // delta <= 0
 				//fmt.Printf("Delta <= 0, skipping\n")
-				continue
+				return nil
 			}
 		}
 
@@ -202,56 +508,70 @@ func (m *Manager) GetBalancesUntil(keychain *Keychain, amount *big.Int, from, un
 		if value.Cmp(BigZero) > 0 {
 			total = new(big.Int).Add(total, balance)
 			target = new(big.Int).Add(target, value)
-			data = append(data, TxData{
-				ID:      accountID,
-				Address: address,
-				Balance: balance,
-				Value:   value,
-			})
+			fees.Add(fees, m.GasFee(account.Gas))
+			account.Value = value
+			data = append(data, account)
 		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Println()
-	result := &Result{Total: total, Target: target, Data: data, GasCost: m.GasCost}
+	if err := m.checkTotalFees(fees); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Total: total, Target: target, Data: data, GasCost: m.GasCost, Fees: fees, LastUsed: lastUsed}
 	return result, nil
 }
 
-func (m *Manager) Collect(keychain *Keychain, result *Result, to common.Address) (*big.Int, error) {
-	total := BigZero
-	units := Units(m.Wei)
-
-	for _, data := range result.Data {
-		key, err := keychain.DerivePrivate(data.ID)
-		if err != nil {
-			return total, err
-		}
+func (m *Manager) Collect(signer Signer, keychain *Keychain, result *Result, to common.Address) (*big.Int, error) {
+	// Plan txs
+	bundle, err := m.PlanCollect(keychain, result, to)
+	if err != nil {
+		return BigZero, err
+	}
 
-		prv, err := crypto.ToECDSA(key.Serialize())
-		if err != nil {
-			return total, err
-		}
+	// Sign & send txs
+	_, total, err := m.execute(signer, bundle)
+	return total, err
+}
 
-		nonce, err := m.Client.PendingNonceAt(m.Context, data.Address)
+func (m *Manager) PlanCollect(keychain *Keychain, result *Result, to common.Address) (*Bundle, error) {
+	bundle := NewBundle(BundleCollect, m.ChainID, keychain)
+	for _, data := range result.Data {
+		nonce, err := m.Nonces.Next(data.Address)
 		if err != nil {
-			return total, err
+			m.discardBundle(bundle)
+			return nil, err
 		}
 
-		printValue := WeiOrEther(data.Value, m.Wei)
-		fmt.Printf("Sending %s %s from %s\n", printValue.String(), units, data.Address.String())
-
-		rawTx := types.NewTransaction(nonce, to, data.Value, m.GasLimit.Uint64(), m.GasPrice, nil)
-		tx, err := types.SignTx(rawTx, types.NewEIP155Signer(m.ChainID), prv)
-		if err != nil {
-			return total, err
+		// Not estimated without target amount
+		gas := data.Gas
+		if gas == 0 {
+			gas = m.GasLimit.Uint64()
 		}
 
-		fmt.Printf("Sending transaction %s\n", tx.Hash().String())
-		if err := m.Client.SendTransaction(m.Context, tx); err != nil {
-			return total, err
-		}
+		bundle.Transactions = append(bundle.Transactions, BundleTx{
+			Index:     data.ID,
+			Path:      keychain.DerivationPath(data.ID),
+			From:      data.Address,
+			To:        to,
+			Nonce:     nonce,
+			Value:     data.Value,
+			Gas:       gas,
+			GasPrice:  m.GasPrice,
+			GasTipCap: m.GasTipCap,
+		})
+	}
 
-		total = total.Add(total, data.Value)
+	if err := m.CheckTotalFees(bundle); err != nil {
+		m.discardBundle(bundle)
+		return nil, err
 	}
 
-	return total, nil
+	return bundle, nil
 }
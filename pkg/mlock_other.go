@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package pkg
+
+import "errors"
+
+func LockMemory() error {
+	return errors.New("Memory locking is not supported on this platform")
+}
@@ -0,0 +1,34 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package pkg
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// Lowest RLIMIT_MEMLOCK to lock future mappings with (Go runtime fails
+// to grow heap once limit is reached)
+const minLockLimit = 256 << 20
+
+// Locks process memory, so unlocked keys (including ones derived later on)
+// are never swapped to disk. With low RLIMIT_MEMLOCK only currently mapped
+// memory is locked and error is returned
+func LockMemory() error {
+	limit := unix.Rlimit{}
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil {
+		return err
+	}
+
+	if limit.Cur == unix.RLIM_INFINITY || limit.Cur >= minLockLimit {
+		return unix.Mlockall(unix.MCL_CURRENT | unix.MCL_FUTURE)
+	}
+
+	if err := unix.Mlockall(unix.MCL_CURRENT); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("Memory lock limit of %d KiB is too low, keys derived after startup may be swapped (raise it with ulimit -l)", limit.Cur>>10)
+}
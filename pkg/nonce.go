@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Nonces of single address
+type nonceAccount struct {
+	local uint64   // Next nonce to be assigned
+	free  []uint64 // Assigned, but unused nonces below local (gaps), reused first
+}
+
+// Assigns nonces per address, safe for concurrent use. Nonces of transactions
+// that failed to send are released and reused, so following transactions
+// don't get stuck behind a gap
+type NonceManager struct {
+	AllowPending bool // Proceed even if address has pending transactions not sent by us
+
+	client   *ethclient.Client
+	ctx      context.Context
+	mutex    sync.Mutex
+	accounts map[common.Address]*nonceAccount
+}
+
+func NewNonceManager(ctx context.Context, client *ethclient.Client) *NonceManager {
+	return &NonceManager{
+		client:   client,
+		ctx:      ctx,
+		accounts: map[common.Address]*nonceAccount{},
+	}
+}
+
+// Fetches latest and pending nonces of new address. Pending transactions
+// can't be sent by us, so they are an error unless allowed
+func (n *NonceManager) sync(address common.Address) error {
+	latest, err := n.client.NonceAt(n.ctx, address, nil)
+	if err != nil {
+		return err
+	}
+
+	pending, err := n.client.PendingNonceAt(n.ctx, address)
+	if err != nil {
+		return err
+	}
+
+	if pending > latest && !n.AllowPending {
+		return fmt.Errorf("Address %s has %d pending transactions not sent by this tool, please wait until they are mined", address.String(), pending-latest)
+	}
+
+	n.accounts[address] = &nonceAccount{local: pending}
+	return nil
+}
+
+// Returns next nonce of address (lowest gap first), syncs unknown address
+func (n *NonceManager) Next(address common.Address) (uint64, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	account, known := n.accounts[address]
+	if !known {
+		if err := n.sync(address); err != nil {
+			return 0, err
+		}
+		account = n.accounts[address]
+	}
+
+	if len(account.free) > 0 {
+		nonce := account.free[0]
+		account.free = account.free[1:]
+		return nonce, nil
+	}
+
+	nonce := account.local
+	account.local++
+	return nonce, nil
+}
+
+// Returns nonce of unsent transaction, so it's reused by next transaction
+func (n *NonceManager) Release(address common.Address, nonce uint64) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	account, known := n.accounts[address]
+	if !known || nonce >= account.local || account.isFree(nonce) {
+		return
+	}
+
+	account.free = append(account.free, nonce)
+	sort.Slice(account.free, func(i, j int) bool { return account.free[i] < account.free[j] })
+
+	// Trailing free nonces are simply not assigned yet
+	for len(account.free) > 0 && account.free[len(account.free)-1] == account.local-1 {
+		account.free = account.free[:len(account.free)-1]
+		account.local--
+	}
+}
+
+func (a *nonceAccount) isFree(nonce uint64) bool {
+	for _, free := range a.free {
+		if free == nonce {
+			return true
+		}
+	}
+
+	return false
+}
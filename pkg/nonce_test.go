@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Node API returning fixed latest and pending nonces
+// (exported, as vendored RPC server only registers exported types)
+type NonceStub struct {
+	Latest, Pending uint64
+}
+
+func (s *NonceStub) GetTransactionCount(address common.Address, block string) (hexutil.Uint64, error) {
+	if block == "pending" {
+		return hexutil.Uint64(s.Pending), nil
+	}
+
+	return hexutil.Uint64(s.Latest), nil
+}
+
+func newTestNonceManager(t *testing.T, latest, pending uint64) *NonceManager {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &NonceStub{Latest: latest, Pending: pending}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+
+	return NewNonceManager(context.Background(), ethclient.NewClient(rpc.DialInProc(server)))
+}
+
+func nextNonces(t *testing.T, n *NonceManager, address common.Address, count int) []uint64 {
+	t.Helper()
+	nonces := []uint64{}
+	for i := 0; i < count; i++ {
+		nonce, err := n.Next(address)
+		if err != nil {
+			t.Fatalf("next nonce error: %v", err)
+		}
+
+		nonces = append(nonces, nonce)
+	}
+
+	return nonces
+}
+
+func checkNonces(t *testing.T, got []uint64, want ...uint64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("nonces %v, want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("nonces %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNonceAllocation(t *testing.T) {
+	n := newTestNonceManager(t, 5, 5)
+	a := common.HexToAddress("0x01")
+	b := common.HexToAddress("0x02")
+
+	checkNonces(t, nextNonces(t, n, a, 3), 5, 6, 7)
+	checkNonces(t, nextNonces(t, n, b, 1), 5)
+	checkNonces(t, nextNonces(t, n, a, 1), 8)
+}
+
+func TestNonceRelease(t *testing.T) {
+	n := newTestNonceManager(t, 5, 5)
+	a := common.HexToAddress("0x01")
+	nextNonces(t, n, a, 4)
+
+	// Gap is reused first
+	n.Release(a, 6)
+	checkNonces(t, nextNonces(t, n, a, 2), 6, 9)
+
+	// Trailing nonces are unassigned
+	n.Release(a, 9)
+	n.Release(a, 8)
+	checkNonces(t, nextNonces(t, n, a, 1), 8)
+
+	// Unknown, unassigned and already released nonces are ignored
+	n.Release(common.HexToAddress("0x02"), 0)
+	n.Release(a, 100)
+	n.Release(a, 5)
+	n.Release(a, 5)
+	checkNonces(t, nextNonces(t, n, a, 3), 5, 9, 10)
+}
+
+func TestNonceForeignPending(t *testing.T) {
+	a := common.HexToAddress("0x01")
+	n := newTestNonceManager(t, 5, 7)
+	if _, err := n.Next(a); err == nil {
+		t.Fatal("expected error for pending transactions not sent by us")
+	}
+
+	n.AllowPending = true
+	checkNonces(t, nextNonces(t, n, a, 2), 7, 8)
+}
@@ -0,0 +1,194 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+const (
+	// BIP-44 derivation path template for Ethereum
+	DefaultPath = "m/44'/60'/{account}'/{chain}/{index}"
+
+	ExternalChain = 0
+	InternalChain = 1
+)
+
+type PathSegment struct {
+	Value    uint32 // Child number (without hardened offset)
+	Hardened bool
+	Index    bool // Value is substituted with address index
+}
+
+// Derivation path with resolved {account} and {chain} variables,
+// {index} is the only variable left
+type Path []PathSegment
+
+func ParsePath(template string, account, chain uint32) (Path, error) {
+	path, indexes, err := parseSegments(template, account, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	if indexes != 1 {
+		return nil, fmt.Errorf("Derivation path %q should contain exactly one {index}", template)
+	}
+
+	return path, nil
+}
+
+// Parses concrete derivation path (without variables)
+func ParseFixedPath(input string) (Path, error) {
+	path, indexes, err := parseSegments(input, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if indexes != 0 {
+		return nil, fmt.Errorf("Derivation path %q should not contain {index}", input)
+	}
+
+	return path, nil
+}
+
+func parseSegments(template string, account, chain uint32) (Path, int, error) {
+	parts := strings.Split(strings.TrimSpace(template), "/")
+	if len(parts) < 2 || parts[0] != "m" {
+		return nil, 0, fmt.Errorf("Invalid derivation path %q (should start with m/)", template)
+	}
+
+	path := Path{}
+	indexes := 0
+	for _, part := range parts[1:] {
+		segment := PathSegment{}
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") || strings.HasSuffix(part, "H") {
+			segment.Hardened = true
+			part = part[:len(part)-1]
+		}
+
+		switch part {
+		case "{account}":
+			segment.Value = account
+		case "{chain}":
+			segment.Value = chain
+		case "{index}":
+			segment.Index = true
+			indexes++
+		default:
+			value, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, 0, fmt.Errorf("Invalid derivation path segment %q in %q", part, template)
+			}
+			segment.Value = uint32(value)
+		}
+
+		if segment.Value >= hdkeychain.HardenedKeyStart {
+			return nil, 0, fmt.Errorf("Derivation path segment %q in %q is out of range", part, template)
+		}
+
+		path = append(path, segment)
+	}
+
+	return path, indexes, nil
+}
+
+// Position of {index} segment, equal to depth of its parent key
+func (p Path) IndexDepth() int {
+	for i, segment := range p {
+		if segment.Index {
+			return i
+		}
+	}
+
+	return len(p)
+}
+
+// Depth of account level key, i.e. depth after the last hardened segment
+// preceding {index} (as hardened segments can't be derived from public keys)
+func (p Path) AccountDepth() int {
+	depth := 0
+	for i := 0; i < p.IndexDepth(); i++ {
+		if p[i].Hardened {
+			depth = i + 1
+		}
+	}
+
+	return depth
+}
+
+// Child number of segment (including hardened offset)
+func (p Path) ChildNumber(depth int, index uint32) (uint32, error) {
+	segment := p[depth]
+	value := segment.Value
+	if segment.Index {
+		if index >= hdkeychain.HardenedKeyStart {
+			return 0, errors.New("Address index is out of range")
+		}
+		value = index
+	}
+
+	if segment.Hardened {
+		value += hdkeychain.HardenedKeyStart
+	}
+
+	return value, nil
+}
+
+// Derives segments in range [from, to) starting with given key
+func (p Path) Derive(key *hdkeychain.ExtendedKey, from, to int, index uint32) (*hdkeychain.ExtendedKey, error) {
+	for i := from; i < to; i++ {
+		child, err := p.ChildNumber(i, index)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err = key.Child(child)
+		if err == hdkeychain.ErrDeriveHardFromPublic {
+			return nil, fmt.Errorf("Path %s requires hardened derivation from extended private key", p.String())
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func (p Path) Format(index uint32) string {
+	parts := []string{"m"}
+	for _, segment := range p {
+		value := segment.Value
+		if segment.Index {
+			value = index
+		}
+
+		part := strconv.FormatUint(uint64(value), 10)
+		if segment.Hardened {
+			part += "'"
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, "/")
+}
+
+func (p Path) String() string {
+	parts := []string{"m"}
+	for _, segment := range p {
+		part := strconv.FormatUint(uint64(segment.Value), 10)
+		if segment.Index {
+			part = "{index}"
+		}
+
+		if segment.Hardened {
+			part += "'"
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, "/")
+}
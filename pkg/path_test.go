@@ -0,0 +1,203 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		template       string
+		account, chain uint32
+		want           string // Path.String(), error substring if invalid
+		valid          bool
+	}{
+		{DefaultPath, 0, ExternalChain, "m/44'/60'/0'/0/{index}", true},
+		{DefaultPath, 3, InternalChain, "m/44'/60'/3'/1/{index}", true},
+		{"m/44'/60'/0'/{index}", 5, 1, "m/44'/60'/0'/{index}", true},
+		{"m/44'/60'/{index}'/0/0", 0, 0, "m/44'/60'/{index}'/0/0", true},
+		{"m/44h/60H/{account}h/{chain}/{index}", 2, 0, "m/44'/60'/2'/0/{index}", true},
+		{" m/{index} ", 0, 0, "m/{index}", true},
+		{"m/2147483647'/{index}", 0, 0, "m/2147483647'/{index}", true},
+		{"44'/60'/0'/0/{index}", 0, 0, "should start with m/", false},
+		{"m", 0, 0, "should start with m/", false},
+		{"", 0, 0, "should start with m/", false},
+		{"m/44'/60'/0'/0", 0, 0, "should contain exactly one {index}", false},
+		{"m/44'/{index}/{index}", 0, 0, "should contain exactly one {index}", false},
+		{"m/44'/60'//{index}", 0, 0, "Invalid derivation path segment", false},
+		{"m/44'/-1/{index}", 0, 0, "Invalid derivation path segment", false},
+		{"m/44''/{index}", 0, 0, "Invalid derivation path segment", false},
+		{"m/44'/{idx}", 0, 0, "Invalid derivation path segment", false},
+		{"m/44'/{index", 0, 0, "Invalid derivation path segment", false},
+		{"m/2147483648/{index}", 0, 0, "out of range", false},
+		{"m/4294967296/{index}", 0, 0, "Invalid derivation path segment", false},
+		{DefaultPath, hdkeychain.HardenedKeyStart, 0, "out of range", false},
+	}
+
+	for _, test := range tests {
+		path, err := ParsePath(test.template, test.account, test.chain)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("%q: unexpected error: %v", test.template, err)
+		case test.valid && path.String() != test.want:
+			t.Errorf("%q: path %s, want %s", test.template, path.String(), test.want)
+		case !test.valid && err == nil:
+			t.Errorf("%q: expected error, got path %s", test.template, path.String())
+		case !test.valid && !strings.Contains(err.Error(), test.want):
+			t.Errorf("%q: error %q, want %q", test.template, err.Error(), test.want)
+		}
+	}
+}
+
+func TestParseFixedPath(t *testing.T) {
+	path, err := ParseFixedPath("m/44'/60'/0'/0/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := path.Format(0); got != "m/44'/60'/0'/0/7" {
+		t.Errorf("path %s, want m/44'/60'/0'/0/7", got)
+	}
+
+	if _, err := ParseFixedPath("m/44'/60'/0'/0/{index}"); err == nil {
+		t.Error("expected error for {index} in fixed path")
+	}
+}
+
+func TestPathDepths(t *testing.T) {
+	tests := []struct {
+		template              string
+		accountDepth, indexAt int
+		formatted             string // Format(7)
+	}{
+		{DefaultPath, 3, 4, "m/44'/60'/0'/0/7"},
+		{"m/44'/60'/0'/{index}", 3, 3, "m/44'/60'/0'/7"},
+		{"m/44'/60'/{index}'/0/0", 2, 2, "m/44'/60'/7'/0/0"},
+		{"m/{index}", 0, 0, "m/7"},
+	}
+
+	for _, test := range tests {
+		path, err := ParsePath(test.template, 0, ExternalChain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if path.AccountDepth() != test.accountDepth || path.IndexDepth() != test.indexAt {
+			t.Errorf("%s: account depth %d, index depth %d, want %d and %d", test.template, path.AccountDepth(), path.IndexDepth(), test.accountDepth, test.indexAt)
+		}
+
+		if got := path.Format(7); got != test.formatted {
+			t.Errorf("%s: formatted %s, want %s", test.template, got, test.formatted)
+		}
+	}
+}
+
+// Addresses of test mnemonic for wallet layouts listed in README
+var pathVectors = []struct {
+	name, template string
+	addresses      []string // Indexes 0, 1, 2
+}{
+	{"default", DefaultPath, []string{
+		"0x9858EfFD232B4033E47d90003D41EC34EcaEda94",
+		"0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0",
+		"0xb6716976A3ebe8D39aCEB04372f22Ff8e6802D7A",
+	}},
+	{"legacy MEW / Ledger", "m/44'/60'/0'/{index}", []string{
+		"0xB8Fd42000d00202DCbCF5e18d6640d656345FD6A",
+		"0x94381955F4028159A477a107510618aDb6B79Eb7",
+		"0xf1e6B562fCb2BdF5579D3A2Fe7069E26A7831053",
+	}},
+	{"Ledger Live", "m/44'/60'/{index}'/0/0", []string{
+		"0x9858EfFD232B4033E47d90003D41EC34EcaEda94",
+		"0x78839F6054d7ed13918bAe0473BA31b1Ca9D7265",
+		"0x07B5FdfEB4E11826D233403Fe8Db0611CCF4c231",
+	}},
+}
+
+func checkAddresses(t *testing.T, name string, keychain *Keychain, addresses []string) {
+	t.Helper()
+	for i, want := range addresses {
+		derived, err := keychain.DeriveAddress(uint32(i))
+		if err != nil {
+			t.Fatalf("%s: derive error: %v", name, err)
+		}
+
+		if derived.Address != common.HexToAddress(want) {
+			t.Errorf("%s: address %d is %s, want %s", name, i, derived.Address.String(), want)
+		}
+	}
+}
+
+func TestPathVectors(t *testing.T) {
+	for _, vector := range pathVectors {
+		checkAddresses(t, vector.name, testKeychain(t, vector.template), vector.addresses)
+	}
+
+	// Account and chain variables
+	path, err := ParsePath(DefaultPath, 1, InternalChain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keychain, err := NewFromMnemonic(testMnemonic, "", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	derived, err := keychain.DeriveAddress(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := common.HexToAddress("0xb6D672fBf4d6a120E4B4D54619cb2bBdcf6EAd20"); derived.Address != want {
+		t.Errorf("address m/44'/60'/1'/1/2 is %s, want %s", derived.Address.String(), want.String())
+	}
+}
+
+func TestPathVectorsAccountKey(t *testing.T) {
+	master, err := MasterFromMnemonic(testMnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vector := range pathVectors {
+		path, err := ParsePath(vector.template, 0, ExternalChain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Account level public key, as exported by wallets
+		account, err := path.Derive(master, 0, path.AccountDepth(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		public, err := account.Neuter()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keychain, err := NewFromKey(public, path)
+		if path[path.IndexDepth()].Hardened {
+			// Hardened {index} can't be derived from public key
+			if err == nil {
+				_, err = keychain.DeriveAddress(0)
+			}
+
+			if err == nil || !strings.Contains(err.Error(), "requires hardened derivation") {
+				t.Errorf("%s: expected hardened derivation error, got %v", vector.name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: keychain error: %v", vector.name, err)
+		}
+
+		checkAddresses(t, vector.name+" (xpub)", keychain, vector.addresses)
+	}
+}
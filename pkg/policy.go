@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Inclusive range of derivation indexes
+type IndexRange struct {
+	From  uint32
+	Until uint32
+}
+
+// Parses "5" or "0-99"
+func ParseIndexRange(input string) (IndexRange, error) {
+	parts := strings.SplitN(strings.TrimSpace(input), "-", 2)
+	from, err := strconv.ParseUint(parts[0], 10, 31)
+	if err != nil {
+		return IndexRange{}, fmt.Errorf("Invalid index range %q", input)
+	}
+
+	until := from
+	if len(parts) == 2 {
+		until, err = strconv.ParseUint(parts[1], 10, 31)
+		if err != nil {
+			return IndexRange{}, fmt.Errorf("Invalid index range %q", input)
+		}
+	}
+
+	if from > until {
+		return IndexRange{}, fmt.Errorf("Invalid index range %q, from should not be greater than until", input)
+	}
+
+	return IndexRange{From: uint32(from), Until: uint32(until)}, nil
+}
+
+func (r IndexRange) Contains(index uint32) bool {
+	return index >= r.From && index <= r.Until
+}
+
+func (r IndexRange) String() string {
+	return fmt.Sprintf("%d-%d", r.From, r.Until)
+}
+
+// Sorts ranges and joins overlapping or adjacent ones
+func mergeIndexRanges(ranges []IndexRange) []IndexRange {
+	sorted := append([]IndexRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].From < sorted[j].From })
+
+	merged := []IndexRange{}
+	for _, r := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && uint64(r.From) <= uint64(merged[last].Until)+1 {
+			if r.Until > merged[last].Until {
+				merged[last].Until = r.Until
+			}
+
+			continue
+		}
+
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// Signing policy, empty fields are not restricted
+type Policy struct {
+	Ranges       []IndexRange     // Allowed derivation indexes (of derived accounts)
+	MaxValue     *big.Int         // Maximum value per transaction (in wei)
+	MaxFee       *big.Int         // Maximum fee per transaction, gas limit times (max) gas price (in wei)
+	Destinations []common.Address // Allowed recipients
+}
+
+func (p *Policy) Check(account Account, tx Transaction) error {
+	if p == nil {
+		return nil
+	}
+
+	if len(p.Ranges) > 0 && len(account.Path) > 0 {
+		allowed := false
+		for _, r := range p.Ranges {
+			allowed = allowed || r.Contains(account.Index)
+		}
+
+		if !allowed {
+			return fmt.Errorf("Policy violation: account %d is outside of allowed ranges", account.Index)
+		}
+	}
+
+	if p.MaxValue != nil && tx.Value().Cmp(p.MaxValue) > 0 {
+		return fmt.Errorf("Policy violation: value %s wei exceeds maximum of %s wei", tx.Value().String(), p.MaxValue.String())
+	}
+
+	if p.MaxFee != nil {
+		fee := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+		if fee.Cmp(p.MaxFee) > 0 {
+			return fmt.Errorf("Policy violation: fee %s wei exceeds maximum of %s wei", fee.String(), p.MaxFee.String())
+		}
+	}
+
+	if len(p.Destinations) > 0 {
+		if tx.To() == nil {
+			return errors.New("Policy violation: contract creation is not allowed")
+		}
+
+		allowed := false
+		for _, destination := range p.Destinations {
+			allowed = allowed || destination == *tx.To()
+		}
+
+		if !allowed {
+			return fmt.Errorf("Policy violation: destination %s is not allowed", tx.To().String())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	policyRecipient = common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	policyOther     = common.HexToAddress("0x0000000000000000000000000000000000000001")
+)
+
+func gwei(value int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(value), big.NewInt(1000000000))
+}
+
+func wei(t *testing.T, amount string) *big.Int {
+	t.Helper()
+	value, err := AmountToWei(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+// Policy as set by --allow-range 0-999 --max-value 1 --max-tx-fee 0.001 --allow-to <recipient>
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	return &Policy{
+		Ranges:       []IndexRange{{From: 0, Until: 999}},
+		MaxValue:     wei(t, "1"),
+		MaxFee:       wei(t, "0.001"),
+		Destinations: []common.Address{policyRecipient},
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := testPolicy(t)
+	derived := Account{Index: 5, Path: "m/44'/60'/0'/0/5"}
+	tests := []struct {
+		name    string
+		policy  *Policy
+		account Account
+		tx      Transaction
+		err     string // Expected error substring, allowed if empty
+	}{
+		{"allowed", policy, derived, types.NewTransaction(0, policyRecipient, wei(t, "1"), TransferGas, gwei(40), nil), ""},
+		{"allowed dynamic fee", policy, derived, NewDynamicFeeTx(big.NewInt(1), 0, policyRecipient, wei(t, "0.5"), TransferGas, gwei(1), gwei(40), nil), ""},
+		{"no policy", nil, Account{Index: 5000, Path: "m/44'/60'/0'/0/5000"}, types.NewContractCreation(0, wei(t, "100"), 1000000, gwei(500), nil), ""},
+		{"outside of ranges", policy, Account{Index: 1000, Path: "m/44'/60'/0'/0/1000"}, types.NewTransaction(0, policyRecipient, big.NewInt(1), TransferGas, gwei(1), nil), "account 1000 is outside of allowed ranges"},
+		{"imported key", policy, Account{Index: 1000}, types.NewTransaction(0, policyRecipient, big.NewInt(1), TransferGas, gwei(1), nil), ""},
+		{"max value", policy, derived, types.NewTransaction(0, policyRecipient, wei(t, "1.000000000000000001"), TransferGas, gwei(1), nil), "value 1000000000000000001 wei exceeds maximum of 1000000000000000000 wei"},
+		{"max value dynamic fee", policy, derived, NewDynamicFeeTx(big.NewInt(1), 0, policyRecipient, wei(t, "2"), TransferGas, gwei(1), gwei(1), nil), "value 2000000000000000000 wei exceeds"},
+		{"max fee", policy, derived, types.NewTransaction(0, policyRecipient, big.NewInt(1), TransferGas, gwei(48), nil), "fee 1008000000000000 wei exceeds maximum of 1000000000000000 wei"},
+		{"max fee at limit", policy, derived, types.NewTransaction(0, policyRecipient, big.NewInt(1), 25000, gwei(40), nil), ""},
+		{"max fee dynamic fee", policy, derived, NewDynamicFeeTx(big.NewInt(1), 0, policyRecipient, big.NewInt(1), TransferGas, gwei(1), gwei(48), nil), "fee 1008000000000000 wei exceeds"},
+		{"destination", policy, derived, types.NewTransaction(0, policyOther, big.NewInt(1), TransferGas, gwei(1), nil), "destination " + policyOther.String() + " is not allowed"},
+		{"destination dynamic fee", policy, derived, NewDynamicFeeTx(big.NewInt(1), 0, policyOther, big.NewInt(1), TransferGas, gwei(1), gwei(1), nil), "destination " + policyOther.String() + " is not allowed"},
+		{"contract creation", policy, derived, types.NewContractCreation(0, big.NewInt(1), TransferGas, gwei(1), nil), "contract creation is not allowed"},
+	}
+
+	for _, test := range tests {
+		err := test.policy.Check(test.account, test.tx)
+		switch {
+		case len(test.err) == 0 && err != nil:
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		case len(test.err) > 0 && err == nil:
+			t.Errorf("%s: expected error", test.name)
+		case len(test.err) > 0 && !strings.Contains(err.Error(), "Policy violation: "+test.err):
+			t.Errorf("%s: error %q, want %q", test.name, err.Error(), test.err)
+		}
+	}
+}
+
+func TestSignerAPIPolicy(t *testing.T) {
+	keychain := testKeychain(t, DefaultPath)
+	api, err := NewSignerAPI(NewRangesHDSigner(keychain, []IndexRange{{From: 0, Until: 1}}), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.Policy = testPolicy(t)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", api); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	signer := &RemoteSigner{client: rpc.DialInProc(server), Timeout: 10 * time.Second}
+	accounts, err := signer.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(accounts) != 2 {
+		t.Fatalf("signer has %d accounts, want 2", len(accounts))
+	}
+
+	account := accounts[1]
+	if _, err := signer.SignTx(account, types.NewTransaction(0, policyRecipient, wei(t, "1"), TransferGas, gwei(40), nil), big.NewInt(1)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	rejected := []Transaction{
+		types.NewTransaction(0, policyRecipient, wei(t, "1.5"), TransferGas, gwei(1), nil),
+		types.NewTransaction(0, policyRecipient, big.NewInt(1), TransferGas, gwei(100), nil),
+		types.NewTransaction(0, policyOther, big.NewInt(1), TransferGas, gwei(1), nil),
+		NewDynamicFeeTx(big.NewInt(1), 0, policyRecipient, wei(t, "1.5"), TransferGas, gwei(1), gwei(1), nil),
+		NewDynamicFeeTx(big.NewInt(1), 0, policyRecipient, big.NewInt(1), TransferGas, gwei(1), gwei(100), nil),
+		NewDynamicFeeTx(big.NewInt(1), 0, policyOther, big.NewInt(1), TransferGas, gwei(1), gwei(1), nil),
+	}
+
+	for i, tx := range rejected {
+		switch tx := tx.(type) {
+		case *types.Transaction:
+			_, err = signer.SignTx(account, tx, big.NewInt(1))
+		case *DynamicFeeTx:
+			_, err = signer.SignDynamicFeeTx(account, tx)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), "Policy violation") {
+			t.Errorf("transaction %d: expected policy violation, got %v", i, err)
+		}
+	}
+
+	// Accounts outside of allowed ranges are not served at all
+	outside, err := keychain.DeriveAddress(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := types.NewTransaction(0, policyRecipient, big.NewInt(1), TransferGas, gwei(1), nil)
+	if _, err := signer.SignTx(Account{Address: outside.Address}, tx, big.NewInt(1)); err == nil || !strings.Contains(err.Error(), "Unknown account") {
+		t.Errorf("expected unknown account error, got %v", err)
+	}
+}
+
+func TestParseIndexRange(t *testing.T) {
+	tests := []struct {
+		input string
+		want  IndexRange
+		valid bool
+	}{
+		{"5", IndexRange{5, 5}, true},
+		{"0-99", IndexRange{0, 99}, true},
+		{" 10-10 ", IndexRange{10, 10}, true},
+		{"2147483647", IndexRange{2147483647, 2147483647}, true},
+		{"2147483648", IndexRange{}, false}, // Hardened
+		{"99-0", IndexRange{}, false},
+		{"-5", IndexRange{}, false},
+		{"1-", IndexRange{}, false},
+		{"a-b", IndexRange{}, false},
+		{"", IndexRange{}, false},
+	}
+
+	for _, test := range tests {
+		got, err := ParseIndexRange(test.input)
+		if test.valid != (err == nil) || got != test.want {
+			t.Errorf("%q: range %v, error %v", test.input, got, err)
+		}
+	}
+}
+
+func TestMergeIndexRanges(t *testing.T) {
+	got := mergeIndexRanges([]IndexRange{{20, 30}, {0, 5}, {6, 10}, {25, 27}, {29, 40}, {50, 50}})
+	want := []IndexRange{{0, 10}, {20, 40}, {50, 50}}
+	if len(got) != len(want) {
+		t.Fatalf("ranges %v, want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("ranges %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+const (
+	// Frame format: HDB1/<number>/<total>/<payload digest>/<frame crc32>/<base64 chunk>
+	qrFramePrefix = "HDB1"
+
+	// Bytes of compressed payload per frame
+	DefaultQRChunkSize = 300
+
+	// Limits of decoded frames, as frame headers are untrusted input
+	maxQRFrames      = 65535
+	maxQRPayloadSize = 64 << 20
+)
+
+// Splits gzipped data into checksummed QR frames
+func EncodeFrames(data []byte, chunkSize int) ([]string, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("Invalid QR chunk size %d", chunkSize)
+	}
+
+	buffer := new(bytes.Buffer)
+	writer, err := gzip.NewWriterLevel(buffer, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	payload := buffer.Bytes()
+	digest := payloadDigest(payload)
+	total := (len(payload) + chunkSize - 1) / chunkSize
+	if total > maxQRFrames {
+		return nil, fmt.Errorf("Payload needs %d QR frames, maximum is %d", total, maxQRFrames)
+	}
+
+	frames := []string{}
+	for i := 0; i < total; i++ {
+		end := (i + 1) * chunkSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+
+		chunk := payload[i*chunkSize : end]
+		frames = append(frames, fmt.Sprintf("%s/%d/%d/%s/%08x/%s", qrFramePrefix, i+1, total, digest, crc32.ChecksumIEEE(chunk), base64.RawURLEncoding.EncodeToString(chunk)))
+	}
+
+	return frames, nil
+}
+
+// Joins QR frames (in any order, duplicates allowed), detecting missing and corrupted frames
+func DecodeFrames(frames []string) ([]byte, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("No QR frames found")
+	}
+
+	chunks := map[int][]byte{}
+	digest, total := "", 0
+	for _, frame := range frames {
+		number, count, frameDigest, chunk, err := parseFrame(frame)
+		if err != nil {
+			return nil, err
+		}
+
+		if total == 0 {
+			digest, total = frameDigest, count
+		}
+
+		if frameDigest != digest || count != total {
+			return nil, fmt.Errorf("QR frames belong to different payloads (%s and %s)", digest, frameDigest)
+		}
+
+		if existing, ok := chunks[number]; ok && !bytes.Equal(existing, chunk) {
+			return nil, fmt.Errorf("QR frame %d was scanned twice with different content", number)
+		}
+
+		chunks[number] = chunk
+	}
+
+	missing := []string{}
+	payload := []byte{}
+	for i := 1; i <= total; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			missing = append(missing, strconv.Itoa(i))
+		}
+
+		payload = append(payload, chunk...)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Missing QR frames %s of %d", strings.Join(missing, ", "), total)
+	}
+
+	if payloadDigest(payload) != digest {
+		return nil, fmt.Errorf("QR payload checksum mismatch")
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, maxQRPayloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxQRPayloadSize {
+		return nil, fmt.Errorf("QR payload exceeds %d bytes", maxQRPayloadSize)
+	}
+
+	return data, nil
+}
+
+func parseFrame(frame string) (int, int, string, []byte, error) {
+	parts := strings.Split(strings.TrimSpace(frame), "/")
+	if len(parts) != 6 || parts[0] != qrFramePrefix {
+		return 0, 0, "", nil, fmt.Errorf("Invalid QR frame %.24q", frame)
+	}
+
+	number, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, "", nil, fmt.Errorf("Invalid QR frame number %q", parts[1])
+	}
+
+	total, err := strconv.Atoi(parts[2])
+	if err != nil || total <= 0 || total > maxQRFrames || number <= 0 || number > total {
+		return 0, 0, "", nil, fmt.Errorf("Invalid QR frame number %s/%s", parts[1], parts[2])
+	}
+
+	chunk, err := base64.RawURLEncoding.DecodeString(parts[5])
+	if err != nil {
+		return 0, 0, "", nil, fmt.Errorf("QR frame %d is corrupted: %s", number, err.Error())
+	}
+
+	if fmt.Sprintf("%08x", crc32.ChecksumIEEE(chunk)) != parts[4] {
+		return 0, 0, "", nil, fmt.Errorf("QR frame %d is corrupted: checksum mismatch", number)
+	}
+
+	return number, total, parts[3], chunk, nil
+}
+
+// First 8 bytes of SHA-256 (hex)
+func payloadDigest(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:8])
+}
+
+func qrMatrix(text string) (*gozxing.BitMatrix, error) {
+	hints := map[gozxing.EncodeHintType]interface{}{
+		gozxing.EncodeHintType_ERROR_CORRECTION: "M",
+	}
+
+	return qrcode.NewQRCodeWriter().Encode(text, gozxing.BarcodeFormat_QR_CODE, 0, 0, hints)
+}
+
+// Renders QR code with half blocks (two modules per character), black on white
+func WriteQRTerminal(w io.Writer, text string) error {
+	matrix, err := qrMatrix(text)
+	if err != nil {
+		return err
+	}
+
+	width, height := matrix.GetWidth(), matrix.GetHeight()
+	for y := 0; y < height; y += 2 {
+		line := "\033[30;47m"
+		for x := 0; x < width; x++ {
+			top := matrix.Get(x, y)
+			bottom := y+1 < height && matrix.Get(x, y+1)
+			switch {
+			case top && bottom:
+				line += "█"
+			case top:
+				line += "▀"
+			case bottom:
+				line += "▄"
+			default:
+				line += " "
+			}
+		}
+
+		if _, err := fmt.Fprintln(w, line+"\033[0m"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func WriteQRPNG(path, text string, scale int) error {
+	matrix, err := qrMatrix(text)
+	if err != nil {
+		return err
+	}
+
+	width, height := matrix.GetWidth(), matrix.GetHeight()
+	img := image.NewGray(image.Rect(0, 0, width*scale, height*scale))
+	for y := 0; y < height*scale; y++ {
+		for x := 0; x < width*scale; x++ {
+			value := color.Gray{Y: 255}
+			if matrix.Get(x/scale, y/scale) {
+				value = color.Gray{Y: 0}
+			}
+			img.SetGray(x, y, value)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return png.Encode(file, img)
+}
+
+func ReadQRImage(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", err
+	}
+
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", err
+	}
+
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+	if err != nil {
+		return "", err
+	}
+
+	return result.GetText(), nil
+}
+
+// Reads QR frames from images (PNG, JPEG, GIF) and text files (one frame per line)
+func ReadFramesDir(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, file := range files {
+		if !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	frames := []string{}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".png", ".jpg", ".jpeg", ".gif":
+			frame, err := ReadQRImage(path)
+			if err != nil {
+				return nil, fmt.Errorf("Could not decode QR code in %s: %s", name, err.Error())
+			}
+
+			frames = append(frames, frame)
+		case ".txt":
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, line := range strings.Split(string(data), "\n") {
+				if len(strings.TrimSpace(line)) > 0 {
+					frames = append(frames, line)
+				}
+			}
+		}
+	}
+
+	return frames, nil
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Builds frame with valid CRC-32 of chunk
+func testFrame(number, total int, digest string, chunk []byte) string {
+	return fmt.Sprintf("%s/%d/%d/%s/%08x/%s", qrFramePrefix, number, total, digest, crc32.ChecksumIEEE(chunk), base64.RawURLEncoding.EncodeToString(chunk))
+}
+
+// Incompressible data
+func testRandomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func checkDecodeError(t *testing.T, frames []string, want string) {
+	t.Helper()
+	_, err := DecodeFrames(frames)
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
+
+func TestQRFramesRoundTrip(t *testing.T) {
+	data := testRandomData(2000)
+	frames, err := EncodeFrames(data, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(frames) < 3 {
+		t.Fatalf("expected multipart payload, got %d frames", len(frames))
+	}
+
+	// Reversed order and duplicate frames
+	scanned := []string{frames[1]}
+	for i := len(frames) - 1; i >= 0; i-- {
+		scanned = append(scanned, frames[i])
+	}
+
+	decoded, err := DecodeFrames(scanned)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if !bytes.Equal(decoded, data) {
+		t.Error("payload changed after round trip")
+	}
+}
+
+func TestQRFramesMissing(t *testing.T) {
+	frames, err := EncodeFrames(testRandomData(2000), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkDecodeError(t, append(frames[:1:1], frames[2:]...), fmt.Sprintf("Missing QR frames 2 of %d", len(frames)))
+}
+
+func TestQRFramesCorrupted(t *testing.T) {
+	frames, err := EncodeFrames(testRandomData(2000), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunk changed, CRC-32 of frame kept
+	parts := strings.Split(frames[1], "/")
+	chunk, _ := base64.RawURLEncoding.DecodeString(parts[5])
+	chunk[0] ^= 0xff
+	parts[5] = base64.RawURLEncoding.EncodeToString(chunk)
+	frames[1] = strings.Join(parts, "/")
+	checkDecodeError(t, frames, "QR frame 2 is corrupted: checksum mismatch")
+}
+
+func TestQRFramesPayloadDigest(t *testing.T) {
+	frames, err := EncodeFrames(testRandomData(2000), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Frame with valid CRC-32, but changed chunk, fails SHA-256 check of payload
+	parts := strings.Split(frames[1], "/")
+	chunk, _ := base64.RawURLEncoding.DecodeString(parts[5])
+	chunk[0] ^= 0xff
+	frames[1] = testFrame(2, len(frames), parts[3], chunk)
+	checkDecodeError(t, frames, "QR payload checksum mismatch")
+}
+
+func TestQRFramesMixed(t *testing.T) {
+	frames, err := EncodeFrames(testRandomData(2000), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := EncodeFrames(testRandomData(1000), 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkDecodeError(t, []string{frames[0], other[1]}, "QR frames belong to different payloads")
+
+	duplicate := testFrame(1, len(frames), strings.Split(frames[0], "/")[3], []byte("other"))
+	checkDecodeError(t, []string{frames[0], duplicate}, "QR frame 1 was scanned twice with different content")
+}
+
+func TestQRFrameCountLimit(t *testing.T) {
+	// Random data doesn't compress, so each byte needs own frame
+	if _, err := EncodeFrames(testRandomData(maxQRFrames+100), 1); err == nil {
+		t.Error("expected error for too many frames")
+	}
+
+	checkDecodeError(t, []string{testFrame(1, maxQRFrames+1, "0000000000000000", []byte{0})}, "Invalid QR frame number")
+	checkDecodeError(t, []string{testFrame(3, 2, "0000000000000000", []byte{0})}, "Invalid QR frame number")
+	checkDecodeError(t, []string{testFrame(0, 2, "0000000000000000", []byte{0})}, "Invalid QR frame number")
+}
+
+func TestQRPayloadSizeLimit(t *testing.T) {
+	// Zeros compress well, so payload fits few frames
+	frames, err := EncodeFrames(make([]byte, maxQRPayloadSize+1), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkDecodeError(t, frames, fmt.Sprintf("QR payload exceeds %d bytes", maxQRPayloadSize))
+
+	// Payload at limit is accepted
+	frames, err = EncodeFrames(make([]byte, maxQRPayloadSize), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := DecodeFrames(frames); err != nil || len(data) != maxQRPayloadSize {
+		t.Errorf("payload at limit: %d bytes, error %v", len(data), err)
+	}
+}
@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Signed transaction with recovered sender
+type RawTx struct {
+	Raw     hexutil.Bytes
+	Tx      Transaction
+	From    common.Address
+	ChainID *big.Int // Nil for unprotected (pre EIP-155) transactions
+}
+
+// Decodes hex-encoded signed transaction (legacy RLP or dynamic fee envelope)
+// and recovers sender
+func DecodeRawTx(input string) (*RawTx, error) {
+	raw, err := hexutil.Decode(strings.TrimSpace(input))
+	if err == hexutil.ErrMissingPrefix {
+		raw, err = hexutil.Decode("0x" + strings.TrimSpace(input))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid raw transaction: %s", err.Error())
+	}
+
+	tx, err := DecodeTransaction(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid raw transaction: %s", err.Error())
+	}
+
+	from, chainID, err := recoverSender(tx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not recover sender of %s: %s", tx.Hash().String(), err.Error())
+	}
+
+	return &RawTx{Raw: raw, Tx: tx, From: from, ChainID: chainID}, nil
+}
+
+// Recovers sender and chain ID of signed transaction, legacy ones with EIP-155
+// signer (or Homestead signer and nil chain ID for unprotected transactions)
+func recoverSender(tx Transaction) (common.Address, *big.Int, error) {
+	if dynamic, ok := tx.(*DynamicFeeTx); ok {
+		from, err := dynamic.Sender()
+		return from, dynamic.ChainID(), err
+	}
+
+	legacy := tx.(*types.Transaction)
+	var chainID *big.Int
+	var signer types.Signer = types.HomesteadSigner{}
+	if legacy.Protected() {
+		chainID = legacy.ChainId()
+		signer = types.NewEIP155Signer(chainID)
+	}
+
+	from, err := types.Sender(signer, legacy)
+	return from, chainID, err
+}
+
+// Reads raw transactions from text file (one per line, # starts comment)
+// or from signed bundle
+func ReadRawTxs(path string) ([]*RawTx, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		bundle, err := ParseBundle(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bundle.Signed() {
+			return nil, errors.New("Bundle is not signed")
+		}
+
+		for _, tx := range bundle.Transactions {
+			lines = append(lines, tx.Raw.String())
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if len(line) > 0 && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	txs := []*RawTx{}
+	for i, line := range lines {
+		tx, err := DecodeRawTx(line)
+		if err != nil {
+			return nil, fmt.Errorf("Transaction %d: %s", i+1, err.Error())
+		}
+
+		txs = append(txs, tx)
+	}
+
+	if len(txs) == 0 {
+		return nil, fmt.Errorf("No transactions found in %s", path)
+	}
+
+	return txs, nil
+}
+
+func (tx *RawTx) Print(wei bool) {
+	units := Units(wei)
+	to := "contract creation"
+	if tx.Tx.To() != nil {
+		to = tx.Tx.To().String()
+	}
+
+	chainID := "none (unprotected, replayable on any chain)"
+	if tx.ChainID != nil {
+		chainID = tx.ChainID.String()
+	}
+
+	fee := new(big.Int).Mul(tx.Tx.GasPrice(), new(big.Int).SetUint64(tx.Tx.Gas()))
+	fmt.Printf("Hash: %s\n", tx.Tx.Hash().String())
+	fmt.Printf("From: %s\n", tx.From.String())
+	fmt.Printf("To: %s\n", to)
+	fmt.Printf("Nonce: %d\n", tx.Tx.Nonce())
+	fmt.Printf("Value: %s %s\n", WeiOrEther(tx.Tx.Value(), wei).String(), units)
+	fmt.Printf("Gas limit: %d\n", tx.Tx.Gas())
+	if dynamic, ok := tx.Tx.(*DynamicFeeTx); ok {
+		fmt.Printf("Type: dynamic fee (EIP-1559)\n")
+		fmt.Printf("Max fee per gas: %s gwei\n", GweiString(dynamic.GasFeeCap()))
+		fmt.Printf("Max priority fee per gas: %s gwei\n", GweiString(dynamic.GasTipCap()))
+	} else {
+		fmt.Printf("Gas price: %s gwei\n", BigToDecimal(tx.Tx.GasPrice()).Shift(-9).String())
+	}
+
+	fmt.Printf("Max fee: %s %s\n", WeiOrEther(fee, wei).String(), units)
+	fmt.Printf("Data: %d bytes\n", len(tx.Tx.Data()))
+	fmt.Printf("Chain ID: %s\n", chainID)
+}
+
+// Sends raw transactions one by one, failures are reported and skipped.
+// Returns number of sent transactions
+func (m *Manager) SendRawTxs(txs []*RawTx) (int, error) {
+	units := Units(m.Wei)
+	total := 0
+	for _, tx := range txs {
+		hash := tx.Tx.Hash().String()
+		if tx.ChainID != nil && tx.ChainID.Cmp(m.ChainID) != 0 {
+			fmt.Printf("FAILED %s: chain ID %s doesn't match node chain ID %s\n", hash, tx.ChainID.String(), m.ChainID.String())
+			continue
+		}
+
+		value := WeiOrEther(tx.Tx.Value(), m.Wei)
+		fmt.Printf("Sending %s %s from %s (nonce %d)\n", value.String(), units, tx.From.String(), tx.Tx.Nonce())
+		if err := m.SendRawTransaction(tx.Raw); err != nil {
+			fmt.Printf("FAILED %s: %s\n", hash, err.Error())
+			continue
+		}
+
+		fmt.Printf("OK %s\n", hash)
+		total++
+	}
+
+	if total < len(txs) {
+		return total, fmt.Errorf("%d transactions of %d failed", len(txs)-total, len(txs))
+	}
+
+	return total, nil
+}
@@ -0,0 +1,180 @@
+package pkg
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	// Transaction states in final report
+	TxPending = "pending" // not mined (yet) or not enough confirmations
+	TxMined   = "mined"
+	TxFailed  = "failed" // mined, but reverted
+
+	ReceiptPollInterval = 4 * time.Second
+)
+
+type Receipt struct {
+	Hash          common.Hash
+	Status        string
+	BlockNumber   uint64
+	GasUsed       uint64
+	GasPrice      *big.Int // Effective gas price, if reported by node
+	Confirmations uint64
+}
+
+// Subset of eth_getTransactionReceipt fields (vendored types.Receipt lacks block number)
+type rpcReceipt struct {
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	Status            *hexutil.Uint64 `json:"status"` // Missing in pre-Byzantium receipts
+	EffectiveGasPrice *hexutil.Big    `json:"effectiveGasPrice"`
+}
+
+func (r Receipt) Settled(confirmations uint64) bool {
+	return r.Status != TxPending && r.Confirmations >= confirmations
+}
+
+// Fee paid, if both gas used and gas price are known
+func (r Receipt) Fee() *big.Int {
+	if r.Status == TxPending || r.GasPrice == nil {
+		return nil
+	}
+
+	return new(big.Int).Mul(r.GasPrice, new(big.Int).SetUint64(r.GasUsed))
+}
+
+func (m *Manager) BlockNumber() (uint64, error) {
+	var head hexutil.Uint64
+	if err := m.RPC.CallContext(m.Context, &head, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+
+	return uint64(head), nil
+}
+
+// Fetches receipt of transaction, status is pending if it's not mined yet
+func (m *Manager) TransactionReceipt(hash common.Hash, head uint64) (Receipt, error) {
+	receipt := Receipt{Hash: hash, Status: TxPending}
+
+	var raw *rpcReceipt
+	if err := m.RPC.CallContext(m.Context, &raw, "eth_getTransactionReceipt", hash); err != nil {
+		return receipt, err
+	}
+
+	if raw == nil {
+		return receipt, nil
+	}
+
+	receipt.Status = TxMined
+	if raw.Status != nil && uint64(*raw.Status) == 0 {
+		receipt.Status = TxFailed
+	}
+
+	receipt.BlockNumber = uint64(raw.BlockNumber)
+	receipt.GasUsed = uint64(raw.GasUsed)
+	if raw.EffectiveGasPrice != nil {
+		receipt.GasPrice = raw.EffectiveGasPrice.ToInt()
+	}
+
+	if head >= receipt.BlockNumber {
+		receipt.Confirmations = head - receipt.BlockNumber + 1
+	}
+
+	return receipt, nil
+}
+
+// Polls receipts until every transaction is mined (or failed) with given
+// number of confirmations or timeout expires. Receipts are fetched again
+// until settled, so transactions dropped by reorg turn back to pending
+func (m *Manager) WaitReceipts(hashes []common.Hash, confirmations uint64, timeout time.Duration) ([]Receipt, error) {
+	receipts := make([]Receipt, len(hashes))
+	for i, hash := range hashes {
+		receipts[i] = Receipt{Hash: hash, Status: TxPending}
+	}
+
+	fmt.Printf("Waiting for %d transactions (%d confirmations, timeout %s)...\n", len(hashes), confirmations, timeout.String())
+	deadline := time.Now().Add(timeout)
+	for {
+		head, err := m.BlockNumber()
+		if err != nil {
+			return receipts, err
+		}
+
+		settled := 0
+		for i := range receipts {
+			if receipts[i].Settled(confirmations) {
+				settled++
+				continue
+			}
+
+			receipt, err := m.TransactionReceipt(receipts[i].Hash, head)
+			if err != nil {
+				return receipts, err
+			}
+
+			if receipt.Status != TxPending && receipts[i].Status == TxPending {
+				fmt.Printf("Transaction %s %s in block %d\n", receipt.Hash.String(), receipt.Status, receipt.BlockNumber)
+			}
+
+			receipts[i] = receipt
+			if receipt.Settled(confirmations) {
+				settled++
+			}
+		}
+
+		remaining := time.Until(deadline)
+		if settled == len(receipts) || remaining <= 0 {
+			return receipts, nil
+		}
+
+		if remaining > ReceiptPollInterval {
+			remaining = ReceiptPollInterval
+		}
+
+		time.Sleep(remaining)
+	}
+}
+
+// Prints final report, returns error unless every transaction is mined
+func PrintReceipts(receipts []Receipt, confirmations uint64, wei bool) error {
+	units := Units(wei)
+	counts := map[string]int{}
+	fees := new(big.Int)
+
+	fmt.Println()
+	for _, receipt := range receipts {
+		status := receipt.Status
+		if status == TxMined && receipt.Confirmations < confirmations {
+			status = TxPending
+		}
+		counts[status]++
+
+		switch receipt.Status {
+		case TxPending:
+			fmt.Printf("- %s: pending\n", receipt.Hash.String())
+		default:
+			line := fmt.Sprintf("- %s: %s in block %d (%d confirmations), gas used %d", receipt.Hash.String(), receipt.Status, receipt.BlockNumber, receipt.Confirmations, receipt.GasUsed)
+			if fee := receipt.Fee(); fee != nil {
+				fees.Add(fees, fee)
+				line += fmt.Sprintf(", fee %s %s", WeiOrEther(fee, wei).String(), units)
+			}
+			fmt.Println(line)
+		}
+	}
+
+	fmt.Printf("Mined: %d, failed: %d, pending: %d\n", counts[TxMined], counts[TxFailed], counts[TxPending])
+	if fees.Sign() > 0 {
+		fmt.Printf("Total fees paid: %s %s\n", WeiOrEther(fees, wei).String(), units)
+	}
+
+	if counts[TxFailed] > 0 || counts[TxPending] > 0 {
+		return fmt.Errorf("Not all transactions were mined (%d failed, %d pending)", counts[TxFailed], counts[TxPending])
+	}
+
+	return nil
+}
@@ -0,0 +1,385 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	SignerVersion = "1.0.1"
+
+	// Unix socket of running hd-agent
+	AgentSocketEnv = "HD_AGENT_SOCK"
+)
+
+// Transaction arguments of Clef account_signTransaction method
+type SignTxArgs struct {
+	From     common.MixedcaseAddress  `json:"from"`
+	To       *common.MixedcaseAddress `json:"to"`
+	Gas      hexutil.Uint64           `json:"gas"`
+	GasPrice *hexutil.Big             `json:"gasPrice,omitempty"`
+	Value    hexutil.Big              `json:"value"`
+	Nonce    hexutil.Uint64           `json:"nonce"`
+	Data     *hexutil.Bytes           `json:"data,omitempty"`
+	Input    *hexutil.Bytes           `json:"input,omitempty"`
+
+	// Dynamic fee (EIP-1559) transactions
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas,omitempty"`
+	ChainID              *hexutil.Big `json:"chainId,omitempty"`
+}
+
+// Only raw transaction is decoded (and verified), as vendored go-ethereum
+// can't decode JSON of dynamic fee transactions returned by Clef
+type SignTxResult struct {
+	Raw hexutil.Bytes   `json:"raw"`
+	Tx  json.RawMessage `json:"tx,omitempty"`
+}
+
+// Signer speaking Clef-style JSON-RPC API over HTTP or unix socket
+type RemoteSigner struct {
+	client  *rpc.Client
+	Timeout time.Duration
+}
+
+// Accepts HTTP(S) URL or unix socket path
+func NewRemoteSigner(url string) (*RemoteSigner, error) {
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoteSigner{client: client, Timeout: 2 * time.Minute}, nil
+}
+
+func (s *RemoteSigner) Accounts() ([]Account, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	addresses := []common.Address{}
+	if err := s.client.CallContext(ctx, &addresses, "account_list"); err != nil {
+		return nil, err
+	}
+
+	accounts := []Account{}
+	for _, address := range addresses {
+		accounts = append(accounts, Account{Address: address})
+	}
+
+	return accounts, nil
+}
+
+// Signed transaction is checked against requested one, so remote side can't alter it
+func (s *RemoteSigner) SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := newSignTxArgs(account, tx)
+	args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	raw, err := s.signTransaction(args)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, signed); err != nil {
+		return nil, err
+	}
+
+	signer := types.NewEIP155Signer(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errors.New("Remote signer returned different transaction")
+	}
+
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, err
+	}
+
+	if sender != account.Address {
+		return nil, fmt.Errorf("Remote signer signed with %s instead of %s", sender.String(), account.Address.String())
+	}
+
+	return signed, nil
+}
+
+func (s *RemoteSigner) SignDynamicFeeTx(account Account, tx *DynamicFeeTx) (*DynamicFeeTx, error) {
+	args := newSignTxArgs(account, tx)
+	args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+	args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	args.ChainID = (*hexutil.Big)(tx.ChainID())
+	raw, err := s.signTransaction(args)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := DecodeDynamicFeeTx(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if signed.SigningHash() != tx.SigningHash() {
+		return nil, errors.New("Remote signer returned different transaction")
+	}
+
+	sender, err := signed.Sender()
+	if err != nil {
+		return nil, err
+	}
+
+	if sender != account.Address {
+		return nil, fmt.Errorf("Remote signer signed with %s instead of %s", sender.String(), account.Address.String())
+	}
+
+	return signed, nil
+}
+
+func newSignTxArgs(account Account, tx Transaction) *SignTxArgs {
+	data := hexutil.Bytes(tx.Data())
+	args := &SignTxArgs{
+		From:  common.NewMixedcaseAddress(account.Address),
+		Gas:   hexutil.Uint64(tx.Gas()),
+		Value: hexutil.Big(*tx.Value()),
+		Nonce: hexutil.Uint64(tx.Nonce()),
+		Data:  &data,
+	}
+
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+
+	return args
+}
+
+// Calls account_signTransaction, returns raw signed transaction
+func (s *RemoteSigner) signTransaction(args *SignTxArgs) (hexutil.Bytes, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	result := SignTxResult{}
+	if err := s.client.CallContext(ctx, &result, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+
+	return result.Raw, nil
+}
+
+// Returns remote signer URL, falling back to running hd-agent if no local key is given
+func SignerURL(url string, local bool) string {
+	if len(url) > 0 || local {
+		return url
+	}
+
+	return os.Getenv(AgentSocketEnv)
+}
+
+// Serves Clef-style account_* methods for given signer (local stand-in for Clef)
+type SignerAPI struct {
+	Policy      *Policy       // Checked before signing (optional)
+	IdleTimeout time.Duration // Stop serving after no requests for this long (optional)
+	signer      Signer
+	chainID     *big.Int
+	accounts    map[common.Address]Account
+	list        []common.Address
+	activity    chan struct{}
+	expired     int32
+}
+
+func NewSignerAPI(signer Signer, chainID *big.Int) (*SignerAPI, error) {
+	accounts, err := signer.Accounts()
+	if err != nil {
+		return nil, err
+	}
+
+	api := &SignerAPI{
+		signer:   signer,
+		chainID:  chainID,
+		accounts: map[common.Address]Account{},
+		activity: make(chan struct{}, 1),
+	}
+
+	for _, account := range accounts {
+		api.accounts[account.Address] = account
+		api.list = append(api.list, account.Address)
+	}
+
+	return api, nil
+}
+
+// account_list
+func (api *SignerAPI) List() ([]common.Address, error) {
+	api.touch()
+	return api.list, nil
+}
+
+// account_version
+func (api *SignerAPI) Version() (string, error) {
+	return SignerVersion, nil
+}
+
+// account_signTransaction
+func (api *SignerAPI) SignTransaction(args SignTxArgs, methodSelector *string) (*SignTxResult, error) {
+	api.touch()
+	account, ok := api.accounts[args.From.Address()]
+	if !ok {
+		return nil, fmt.Errorf("Unknown account %s", args.From.Address().String())
+	}
+
+	if args.To == nil {
+		return nil, errors.New("Contract creation is not supported")
+	}
+
+	data := []byte{}
+	if args.Input != nil {
+		data = *args.Input
+	} else if args.Data != nil {
+		data = *args.Data
+	}
+
+	value := (*big.Int)(&args.Value)
+	fmt.Printf("Signing tx %d from %s (%s) to %s, value %s wei\n", uint64(args.Nonce), account.Address.String(), account.Path, args.To.Address().String(), value.String())
+	if args.MaxFeePerGas != nil {
+		return api.signDynamicFeeTx(account, args, data)
+	}
+
+	if args.GasPrice == nil {
+		return nil, errors.New("Missing gas price")
+	}
+
+	tx := types.NewTransaction(uint64(args.Nonce), args.To.Address(), value, uint64(args.Gas), args.GasPrice.ToInt(), data)
+	if err := api.Policy.Check(account, tx); err != nil {
+		fmt.Printf("Rejected: %s\n", err.Error())
+		return nil, err
+	}
+
+	signed, err := api.signer.SignTx(account, tx, api.chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := json.Marshal(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignTxResult{Raw: raw, Tx: decoded}, nil
+}
+
+func (api *SignerAPI) signDynamicFeeTx(account Account, args SignTxArgs, data []byte) (*SignTxResult, error) {
+	if args.ChainID != nil && args.ChainID.ToInt().Cmp(api.chainID) != 0 {
+		return nil, fmt.Errorf("Chain ID %s doesn't match signer chain ID %s", args.ChainID.ToInt().String(), api.chainID.String())
+	}
+
+	if args.MaxPriorityFeePerGas == nil {
+		return nil, errors.New("Missing max priority fee per gas")
+	}
+
+	tx := NewDynamicFeeTx(api.chainID, uint64(args.Nonce), args.To.Address(), args.Value.ToInt(), uint64(args.Gas), args.MaxPriorityFeePerGas.ToInt(), args.MaxFeePerGas.ToInt(), data)
+	if err := api.Policy.Check(account, tx); err != nil {
+		fmt.Printf("Rejected: %s\n", err.Error())
+		return nil, err
+	}
+
+	signed, err := api.signer.SignDynamicFeeTx(account, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignTxResult{Raw: raw}, nil
+}
+
+// Serves signer API over HTTP (address) or unix socket (path)
+func (api *SignerAPI) Serve(httpAddr, socket string) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", api); err != nil {
+		return err
+	}
+
+	if len(socket) > 0 {
+		// Never replace socket of another agent
+		if _, err := os.Lstat(socket); err == nil {
+			return fmt.Errorf("Socket %s already exists, please remove it if no agent is using it", socket)
+		}
+
+		listener, err := listenUnix(socket)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Listening on %s\n", socket)
+		go api.expire(listener)
+		return api.served(server.ServeListener(listener))
+	}
+
+	listener, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Listening on http://%s\n", listener.Addr().String())
+	go api.expire(listener)
+	return api.served(rpc.NewHTTPServer(nil, []string{"localhost"}, rpc.DefaultHTTPTimeouts, server).Serve(listener))
+}
+
+func (api *SignerAPI) touch() {
+	select {
+	case api.activity <- struct{}{}:
+	default:
+	}
+}
+
+// Closes listener after idle timeout
+func (api *SignerAPI) expire(listener net.Listener) {
+	if api.IdleTimeout <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(api.IdleTimeout)
+	for {
+		select {
+		case <-api.activity:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(api.IdleTimeout)
+		case <-timer.C:
+			fmt.Printf("Idle for %s, stopping\n", api.IdleTimeout)
+			atomic.StoreInt32(&api.expired, 1)
+			listener.Close()
+			return
+		}
+	}
+}
+
+// Listener errors are expected after idle timeout
+func (api *SignerAPI) served(err error) error {
+	if atomic.LoadInt32(&api.expired) == 1 {
+		return nil
+	}
+
+	return err
+}
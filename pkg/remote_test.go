@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Clef account API, responds with dynamic fee transaction JSON lacking gasPrice
+// (exported, as vendored RPC server only registers exported types)
+type ClefStub struct {
+	key *ecdsa.PrivateKey
+}
+
+func (c *ClefStub) SignTransaction(args SignTxArgs, methodSelector *string) (map[string]interface{}, error) {
+	tx := NewDynamicFeeTx(args.ChainID.ToInt(), uint64(args.Nonce), args.To.Address(), args.Value.ToInt(), uint64(args.Gas), args.MaxPriorityFeePerGas.ToInt(), args.MaxFeePerGas.ToInt(), *args.Data)
+	signed, err := tx.Sign(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	v, r, s := signed.RawSignatureValues()
+	return map[string]interface{}{
+		"raw": hexutil.Bytes(raw),
+		"tx": map[string]interface{}{
+			"type":                 "0x2",
+			"chainId":              (*hexutil.Big)(signed.ChainID()),
+			"nonce":                hexutil.Uint64(signed.Nonce()),
+			"to":                   signed.To(),
+			"gas":                  hexutil.Uint64(signed.Gas()),
+			"maxPriorityFeePerGas": (*hexutil.Big)(signed.GasTipCap()),
+			"maxFeePerGas":         (*hexutil.Big)(signed.GasFeeCap()),
+			"value":                (*hexutil.Big)(signed.Value()),
+			"input":                hexutil.Bytes(signed.Data()),
+			"accessList":           []interface{}{},
+			"v":                    (*hexutil.Big)(v),
+			"r":                    (*hexutil.Big)(r),
+			"s":                    (*hexutil.Big)(s),
+			"yParity":              (*hexutil.Big)(v),
+			"hash":                 signed.Hash(),
+		},
+	}, nil
+}
+
+func TestRemoteSignerDynamicFeeTx(t *testing.T) {
+	key, err := crypto.HexToECDSA(signedVector.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", &ClefStub{key: key}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	signer := &RemoteSigner{client: rpc.DialInProc(server), Timeout: 10 * time.Second}
+	account := Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	to := common.HexToAddress("0x095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	value, _ := new(big.Int).SetString("1000000000000000000", 10)
+	tx := NewDynamicFeeTx(big.NewInt(5), 7, to, value, 21000, big.NewInt(1500000000), big.NewInt(30000000000), nil)
+
+	signed, err := signer.SignDynamicFeeTx(account, tx)
+	if err != nil {
+		t.Fatalf("sign error: %v", err)
+	}
+
+	if got := signed.Hash().Hex(); got != signedVector.hash {
+		t.Errorf("hash %s, want %s", got, signedVector.hash)
+	}
+
+	// Transaction signed by another key is rejected
+	other := Account{Address: common.HexToAddress("0x0000000000000000000000000000000000000001")}
+	if _, err := signer.SignDynamicFeeTx(other, tx); err == nil {
+		t.Error("expected error for transaction signed by another account")
+	}
+}
@@ -0,0 +1,230 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Minimal gas price bump (in percent) accepted by geth to replace pending transaction
+const DefaultPriceBump = 10
+
+// Pending transaction and its sender
+type StuckTx struct {
+	Tx   Transaction
+	From common.Address
+}
+
+// Subset of eth_getTransactionByHash fields, vendored client can't decode
+// dynamic fee transactions
+type rpcTransaction struct {
+	Type                 *hexutil.Uint64 `json:"type"` // Missing on pre-Berlin nodes
+	BlockNumber          *hexutil.Big    `json:"blockNumber"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	To                   *common.Address `json:"to"`
+	Value                hexutil.Big     `json:"value"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Input                hexutil.Bytes   `json:"input"`
+	V                    hexutil.Big     `json:"v"`
+	R                    hexutil.Big     `json:"r"`
+	S                    hexutil.Big     `json:"s"`
+}
+
+// Returns minimal gas price of replacement transaction (rounded up)
+func BumpGasPrice(price *big.Int, percent uint64) *big.Int {
+	bumped := new(big.Int).Mul(price, new(big.Int).SetUint64(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// Returns nil if transaction is unknown to node
+func (m *Manager) transactionByHash(hash common.Hash) (*rpcTransaction, error) {
+	var tx *rpcTransaction
+	if err := m.RPC.CallContext(m.Context, &tx, "eth_getTransactionByHash", hash); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Looks up pending transaction by hash and recovers its sender
+func (m *Manager) StuckTransaction(hash common.Hash) (*StuckTx, error) {
+	known, err := m.transactionByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if known == nil {
+		return nil, fmt.Errorf("Transaction %s not found", hash.String())
+	}
+
+	if known.BlockNumber != nil {
+		return nil, fmt.Errorf("Transaction %s is already mined", hash.String())
+	}
+
+	var tx Transaction
+	if known.Type != nil && uint64(*known.Type) == DynamicFeeTxType {
+		tx, err = known.dynamicFeeTx(hash)
+	} else {
+		tx, _, err = m.Client.TransactionByHash(m.Context, hash)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	from, _, err := recoverSender(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StuckTx{Tx: tx, From: from}, nil
+}
+
+// Rebuilds signed transaction, hash mismatch means unsupported fields (access list)
+func (tx *rpcTransaction) dynamicFeeTx(hash common.Hash) (*DynamicFeeTx, error) {
+	if tx.ChainID == nil || tx.MaxFeePerGas == nil || tx.MaxPriorityFeePerGas == nil || tx.To == nil {
+		return nil, fmt.Errorf("Transaction %s is not supported", hash.String())
+	}
+
+	unsigned := NewDynamicFeeTx(tx.ChainID.ToInt(), uint64(tx.Nonce), *tx.To, tx.Value.ToInt(), uint64(tx.Gas), tx.MaxPriorityFeePerGas.ToInt(), tx.MaxFeePerGas.ToInt(), tx.Input)
+	sig := make([]byte, 65)
+	r, s := tx.R.ToInt().Bytes(), tx.S.ToInt().Bytes()
+	if len(r) > 32 || len(s) > 32 || tx.V.ToInt().BitLen() > 1 {
+		return nil, fmt.Errorf("Transaction %s has invalid signature", hash.String())
+	}
+
+	copy(sig[32-len(r):32], r)
+	copy(sig[64-len(s):64], s)
+	sig[64] = byte(tx.V.ToInt().Uint64())
+	signed, err := unsigned.WithSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	if signed.Hash() != hash {
+		return nil, fmt.Errorf("Transaction %s is not supported", hash.String())
+	}
+
+	return signed, nil
+}
+
+// Checks whether transaction nonce is still unused
+func (m *Manager) CheckReplaceable(stuck *StuckTx) error {
+	nonce, err := m.Client.NonceAt(m.Context, stuck.From, nil)
+	if err != nil {
+		return err
+	}
+
+	if nonce > stuck.Tx.Nonce() {
+		return fmt.Errorf("Nonce %d of %s is already used by mined transaction", stuck.Tx.Nonce(), stuck.From.String())
+	}
+
+	return nil
+}
+
+// Builds replacement transaction with same nonce and type: same payload to
+// speed up or zero-value self-transfer to cancel. Gas price (both fee caps of
+// dynamic fee transaction) is bumped by given percent over original one, or
+// set to manager gas price (priority fee) if it's higher
+func (m *Manager) ReplacementTx(stuck *StuckTx, cancel bool, bump uint64) Transaction {
+	original := stuck.Tx
+	gasPrice := maxBig(BumpGasPrice(original.GasPrice(), bump), m.GasPrice)
+
+	to, value, gas, data := original.To(), original.Value(), original.Gas(), original.Data()
+	if cancel {
+		to, value, gas, data = &stuck.From, big.NewInt(0), m.GasLimit.Uint64(), nil
+	}
+
+	if dynamic, ok := original.(*DynamicFeeTx); ok {
+		tip := BumpGasPrice(dynamic.GasTipCap(), bump)
+		if m.GasTipCap != nil {
+			tip = maxBig(tip, m.GasTipCap)
+		}
+
+		return NewDynamicFeeTx(dynamic.ChainID(), original.Nonce(), *to, value, gas, tip, maxBig(gasPrice, tip), data)
+	}
+
+	if to == nil {
+		return types.NewContractCreation(original.Nonce(), value, gas, gasPrice, data)
+	}
+
+	return types.NewTransaction(original.Nonce(), *to, value, gas, gasPrice, data)
+}
+
+func maxBig(x, y *big.Int) *big.Int {
+	if x.Cmp(y) < 0 {
+		return y
+	}
+
+	return x
+}
+
+// Signs and sends replacement transaction
+func (m *Manager) Replace(signer Signer, account Account, stuck *StuckTx, cancel bool, bump uint64) (Transaction, error) {
+	if account.Address != stuck.From {
+		return nil, errors.New("Signing account doesn't match transaction sender")
+	}
+
+	var signed Transaction
+	var err error
+	switch tx := m.ReplacementTx(stuck, cancel, bump).(type) {
+	case *DynamicFeeTx:
+		signed, err = signer.SignDynamicFeeTx(account, tx)
+	case *types.Transaction:
+		signed, err = signer.SignTx(account, tx, m.ChainID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := EncodeTransaction(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	units := Units(m.Wei)
+	fee := WeiOrEther(new(big.Int).Mul(signed.GasPrice(), new(big.Int).SetUint64(signed.Gas())), m.Wei)
+	fmt.Printf("Replacing %s (nonce %d, gas price %s gwei)\n", stuck.Tx.Hash().String(), stuck.Tx.Nonce(), BigToDecimal(stuck.Tx.GasPrice()).Shift(-9).String())
+	fmt.Printf("Sending transaction %s (gas price %s gwei, max fee %s %s)\n", signed.Hash().String(), BigToDecimal(signed.GasPrice()).Shift(-9).String(), fee.String(), units)
+	if err := m.SendRawTransaction(raw); err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+// Stuck transactions of journal (sent, but not mined yet)
+func (m *Manager) JournalStuckTxs(journal *Journal) ([]int, []*StuckTx, error) {
+	indexes := []int{}
+	stuck := []*StuckTx{}
+	for i, data := range journal.Bundle.Transactions {
+		if journal.States[i].State != JournalBroadcast {
+			continue
+		}
+
+		tx, err := data.SignedTransaction()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		candidate := &StuckTx{Tx: tx, From: data.From}
+		if err := m.CheckReplaceable(candidate); err != nil {
+			fmt.Printf("Skipping %s: %s\n", data.Hash.String(), err.Error())
+			continue
+		}
+
+		indexes = append(indexes, i)
+		stuck = append(stuck, candidate)
+	}
+
+	return indexes, stuck, nil
+}
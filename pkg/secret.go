@@ -0,0 +1,166 @@
+package pkg
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Environment variable with keystore password (prompted for otherwise)
+const KeystorePasswordEnv = "KEYSTORE_PASSWORD"
+
+// Secret sources:
+//   "-"             stdin (echo is disabled on terminals)
+//   "prompt"        terminal prompt with echo disabled
+//   "env:NAME"      environment variable
+//   "file:PATH"     file
+//   "keystore:PATH" Web3 Secret Storage (V3 keystore) file
+func isSecretSource(spec string) bool {
+	if spec == "-" || spec == "prompt" {
+		return true
+	}
+
+	for _, prefix := range []string{"env:", "file:", "keystore:"} {
+		if strings.HasPrefix(spec, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reads secret from source, bare values are treated as file paths
+func ReadSecret(spec, prompt string) (string, error) {
+	if !isSecretSource(spec) {
+		spec = "file:" + spec
+	}
+
+	return readSource(spec, prompt)
+}
+
+// Reads key from source, bare values are treated as literal keys and
+// are refused unless insecure (as they leak into shell history and ps output)
+func ReadKey(spec, prompt string, insecure bool) (string, error) {
+	if isSecretSource(spec) {
+		key, err := readSource(spec, prompt)
+		return strings.TrimSpace(key), err
+	}
+
+	if !insecure {
+		return "", errors.New("Refusing to read secret from command line, please use -, prompt, env:NAME, file:PATH or keystore:PATH source (or --insecure flag)")
+	}
+
+	return spec, nil
+}
+
+func readSource(spec, prompt string) (string, error) {
+	switch {
+	case spec == "-":
+		if terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return readPassword(prompt)
+		}
+
+		line, err := readLine(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+
+		if len(line) == 0 {
+			return "", errors.New("Empty input on stdin")
+		}
+
+		return line, nil
+	case spec == "prompt":
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return "", errors.New("Secret prompt requires a terminal")
+		}
+
+		return readPassword(prompt)
+	case strings.HasPrefix(spec, "env:"):
+		name := strings.TrimPrefix(spec, "env:")
+		value := trimNewline(os.Getenv(name))
+		if len(value) == 0 {
+			return "", fmt.Errorf("Environment variable %s is empty", name)
+		}
+
+		return value, nil
+	case strings.HasPrefix(spec, "file:"):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(spec, "file:"))
+		if err != nil {
+			return "", err
+		}
+
+		return trimNewline(string(data)), nil
+	case strings.HasPrefix(spec, "keystore:"):
+		path := strings.TrimPrefix(spec, "keystore:")
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		password := os.Getenv(KeystorePasswordEnv)
+		if len(password) == 0 {
+			password, err = readSource("prompt", fmt.Sprintf("Enter password for %s: ", path))
+			if err != nil {
+				return "", err
+			}
+		}
+
+		key, err := DecryptKeystore(data, password)
+		if err != nil {
+			return "", err
+		}
+
+		// Keys with leading zero bytes may be stored without them
+		return hex.EncodeToString(common.LeftPadBytes(key, 32)), nil
+	}
+
+	return "", fmt.Errorf("Unknown secret source %q", spec)
+}
+
+func readPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	data, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return trimNewline(string(data)), nil
+}
+
+// Reads single line byte by byte, so that no input is buffered
+// ahead (stdin is also used for confirmations later on)
+func readLine(file *os.File) (string, error) {
+	line := []byte{}
+	buf := make([]byte, 1)
+	for {
+		n, err := file.Read(buf)
+		if n == 0 || err != nil {
+			if len(line) > 0 {
+				break
+			}
+
+			return "", err
+		}
+
+		if buf[0] == '\n' {
+			break
+		}
+
+		line = append(line, buf[0])
+	}
+
+	return trimNewline(string(line)), nil
+}
+
+// Strips line ending only, as other whitespace may be part of passwords and passphrases
+func trimNewline(value string) string {
+	return strings.TrimRight(value, "\r\n")
+}
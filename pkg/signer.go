@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signing account, index and path are only known for HD keys
+type Account struct {
+	Address common.Address `json:"address"`
+	Index   uint32         `json:"index"`
+	Path    string         `json:"path,omitempty"`
+}
+
+// Signs transactions on behalf of accounts it holds keys for
+type Signer interface {
+	Accounts() ([]Account, error)
+	SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	SignDynamicFeeTx(account Account, tx *DynamicFeeTx) (*DynamicFeeTx, error)
+}
+
+// In-process signer for derived keys in given index ranges
+type HDSigner struct {
+	keychain *Keychain
+	ranges   []IndexRange
+}
+
+// Signer for derived keys in range [from, until]
+func NewHDSigner(keychain *Keychain, from, until uint32) *HDSigner {
+	if until < from {
+		return &HDSigner{keychain: keychain}
+	}
+
+	return NewRangesHDSigner(keychain, []IndexRange{{From: from, Until: until}})
+}
+
+// Signer for derived keys in union of ranges (indexes between them are not derived)
+func NewRangesHDSigner(keychain *Keychain, ranges []IndexRange) *HDSigner {
+	return &HDSigner{keychain: keychain, ranges: mergeIndexRanges(ranges)}
+}
+
+func (s *HDSigner) Accounts() ([]Account, error) {
+	accounts := []Account{}
+	for _, r := range s.ranges {
+		it := s.keychain.Iterate(r.From, r.Until)
+		for it.Next() {
+			derived := it.Key()
+			accounts = append(accounts, Account{
+				Address: derived.Address,
+				Index:   derived.Index,
+				Path:    s.keychain.DerivationPath(derived.Index),
+			})
+		}
+
+		it.Close()
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return accounts, nil
+}
+
+func (s *HDSigner) SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	prv, err := s.privateKey(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.SignTx(tx, types.NewEIP155Signer(chainID), prv)
+}
+
+func (s *HDSigner) SignDynamicFeeTx(account Account, tx *DynamicFeeTx) (*DynamicFeeTx, error) {
+	prv, err := s.privateKey(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.Sign(prv)
+}
+
+func (s *HDSigner) privateKey(account Account) (*ecdsa.PrivateKey, error) {
+	key, err := s.keychain.DerivePrivate(account.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	prv := key.ToECDSA()
+	if address := crypto.PubkeyToAddress(prv.PublicKey); address != account.Address {
+		return nil, fmt.Errorf("Account %d derives to %s, not %s", account.Index, address.String(), account.Address.String())
+	}
+
+	return prv, nil
+}
+
+// In-process signer for single private key
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (s *KeySigner) Accounts() ([]Account, error) {
+	return []Account{{Address: s.address}}, nil
+}
+
+func (s *KeySigner) SignTx(account Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if account.Address != s.address {
+		return nil, fmt.Errorf("Unknown account %s", account.Address.String())
+	}
+
+	return types.SignTx(tx, types.NewEIP155Signer(chainID), s.key)
+}
+
+func (s *KeySigner) SignDynamicFeeTx(account Account, tx *DynamicFeeTx) (*DynamicFeeTx, error) {
+	if account.Address != s.address {
+		return nil, fmt.Errorf("Unknown account %s", account.Address.String())
+	}
+
+	return tx.Sign(s.key)
+}
+
+// Returns signer account with given address, or the only one if address is empty
+func SelectAccount(signer Signer, address string) (Account, error) {
+	accounts, err := signer.Accounts()
+	if err != nil {
+		return Account{}, err
+	}
+
+	if len(address) == 0 {
+		if len(accounts) != 1 {
+			return Account{}, fmt.Errorf("Signer holds %d accounts, please select one", len(accounts))
+		}
+
+		return accounts[0], nil
+	}
+
+	if !common.IsHexAddress(address) {
+		return Account{}, errors.New("Invalid account address")
+	}
+
+	target := common.HexToAddress(address)
+	for _, account := range accounts {
+		if account.Address == target {
+			return account, nil
+		}
+	}
+
+	return Account{}, fmt.Errorf("Signer doesn't hold account %s", target.String())
+}
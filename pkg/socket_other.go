@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package pkg
+
+import (
+	"net"
+	"os"
+)
+
+func listenUnix(socket string) (net.Listener, error) {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
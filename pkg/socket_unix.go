@@ -0,0 +1,18 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package pkg
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// Creates unix socket only accessible by owner (umask is set before bind,
+// so there is no window in which other users could connect)
+func listenUnix(socket string) (net.Listener, error) {
+	mask := unix.Umask(0177)
+	defer unix.Umask(mask)
+	return net.Listen("unix", socket)
+}
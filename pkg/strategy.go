@@ -0,0 +1,175 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/shopspring/decimal"
+)
+
+// Recent blocks to take transaction prices from (percentile strategy)
+const DefaultStrategyBlocks = 20
+
+// Chooses gas price of legacy transactions
+type GasPriceStrategy interface {
+	GasPrice(m *Manager) (*big.Int, error)
+	String() string
+}
+
+// Gas price suggested by node (eth_gasPrice)
+type NodeStrategy struct{}
+
+func (s NodeStrategy) GasPrice(m *Manager) (*big.Int, error) {
+	return m.Client.SuggestGasPrice(m.Context)
+}
+
+func (s NodeStrategy) String() string {
+	return "node suggestion"
+}
+
+// Percentile of gas prices paid by transactions in recent blocks
+type PercentileStrategy struct {
+	Percentile int // 0 to 100
+	Blocks     int
+}
+
+// Subset of eth_getBlockByNumber fields (vendored client can't decode dynamic fee transactions)
+type rpcBlock struct {
+	Transactions []struct {
+		GasPrice *hexutil.Big `json:"gasPrice"` // Effective gas price for dynamic fee transactions
+	} `json:"transactions"`
+}
+
+func (s PercentileStrategy) GasPrice(m *Manager) (*big.Int, error) {
+	head, err := m.BlockNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*rpcBlock, s.Blocks)
+	batch := []rpc.BatchElem{}
+	for i := 0; i < s.Blocks && uint64(i) <= head; i++ {
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.Uint64(head - uint64(i)), true},
+			Result: &blocks[i],
+		})
+	}
+
+	if err := m.RPC.BatchCallContext(m.Context, batch); err != nil {
+		return nil, err
+	}
+
+	prices := []*big.Int{}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+
+		if blocks[i] == nil {
+			continue
+		}
+
+		for _, tx := range blocks[i].Transactions {
+			if tx.GasPrice != nil {
+				prices = append(prices, tx.GasPrice.ToInt())
+			}
+		}
+	}
+
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("No transactions found in last %d blocks", len(batch))
+	}
+
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Cmp(prices[j]) < 0 })
+	return prices[(len(prices)-1)*s.Percentile/100], nil
+}
+
+func (s PercentileStrategy) String() string {
+	return fmt.Sprintf("percentile %d of last %d blocks", s.Percentile, s.Blocks)
+}
+
+// Gas price given by user
+type FixedStrategy struct {
+	Price *big.Int
+}
+
+func (s FixedStrategy) GasPrice(m *Manager) (*big.Int, error) {
+	return s.Price, nil
+}
+
+func (s FixedStrategy) String() string {
+	return fmt.Sprintf("fixed %s gwei", GweiString(s.Price))
+}
+
+// Node suggestion multiplied by factor (rounded up)
+type MultiplierStrategy struct {
+	Multiplier decimal.Decimal
+}
+
+func (s MultiplierStrategy) GasPrice(m *Manager) (*big.Int, error) {
+	suggested, err := m.Client.SuggestGasPrice(m.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	price := BigToDecimal(suggested).Mul(s.Multiplier).Ceil()
+	result, done := new(big.Int).SetString(price.String(), 10)
+	if !done {
+		return nil, errors.New("Error calculating gas price")
+	}
+
+	return result, nil
+}
+
+func (s MultiplierStrategy) String() string {
+	return fmt.Sprintf("node suggestion multiplied by %s", s.Multiplier.String())
+}
+
+// Parses strategy name with optional arguments: node, percentile:PERCENT[:BLOCKS],
+// fixed:GWEI or multiplier:FACTOR
+func ParseGasStrategy(input string) (GasPriceStrategy, error) {
+	parts := strings.Split(strings.TrimSpace(input), ":")
+	name, args := parts[0], parts[1:]
+	switch {
+	case name == "node" && len(args) == 0:
+		return NodeStrategy{}, nil
+	case name == "percentile" && (len(args) == 1 || len(args) == 2):
+		percentile, err := strconv.ParseUint(args[0], 10, 8)
+		if err != nil || percentile > 100 {
+			return nil, fmt.Errorf("Invalid percentile %q", args[0])
+		}
+
+		blocks := uint64(DefaultStrategyBlocks)
+		if len(args) == 2 {
+			blocks, err = strconv.ParseUint(args[1], 10, 16)
+			if err != nil || blocks == 0 {
+				return nil, fmt.Errorf("Invalid number of blocks %q", args[1])
+			}
+		}
+
+		return PercentileStrategy{Percentile: int(percentile), Blocks: int(blocks)}, nil
+	case name == "fixed" && len(args) == 1:
+		price, err := GweiToWei(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return FixedStrategy{Price: price}, nil
+	case name == "multiplier" && len(args) == 1:
+		multiplier, err := decimal.NewFromString(args[0])
+		if err != nil || multiplier.Sign() <= 0 {
+			return nil, fmt.Errorf("Invalid multiplier %q", args[0])
+		}
+
+		return MultiplierStrategy{Multiplier: multiplier}, nil
+	}
+
+	return nil, errors.New("Unknown gas price strategy, please use node, percentile:PERCENT[:BLOCKS], fixed:GWEI or multiplier:FACTOR")
+}
@@ -12,13 +12,17 @@ type TxData struct {
 	Address common.Address
 	Balance *big.Int // Real balance
 	Value   *big.Int // Transferred value (balance - fees)
+	Nonce   uint64   // Number of sent transactions
+	Gas     uint64   // Estimated gas limit of transfer
 }
 
 type Result struct {
-	Data    []TxData
-	GasCost *big.Int
-	Target  *big.Int // Target balance
-	Total   *big.Int // Total available balance
+	Data     []TxData
+	GasCost  *big.Int
+	Fees     *big.Int // Maximum fees of selected accounts
+	Target   *big.Int // Target balance
+	Total    *big.Int // Total available balance
+	LastUsed int      // Highest used account number (-1 if none)
 }
 
 func (res Result) PrintConfirmation(destination common.Address, amount *big.Int, wei bool) {
@@ -35,6 +39,14 @@ func (res Result) PrintConfirmation(destination common.Address, amount *big.Int,
 		fmt.Printf("- Will send %s %s from %s\n", value, units, data.Address.String())
 	}
 
+	if res.LastUsed >= 0 {
+		fmt.Printf("Highest used account number: %d\n", res.LastUsed)
+	}
+
+	if res.Fees != nil {
+		fmt.Printf("Maximum fees: %s %s\n", WeiOrEther(res.Fees, wei).String(), units)
+	}
+
 	fmt.Printf("Destination: %s\n", destination.String())
 	fmt.Println()
 	fmt.Printf("Do you wish to proceed? [yes/no]: ")
@@ -50,4 +62,8 @@ func (res Result) PrintSummary(wei bool) {
 		balance := WeiOrEther(data.Balance, wei)
 		fmt.Printf("- Address №%d (%s) has %s %s\n", data.ID, data.Address.String(), balance.String(), units)
 	}
+
+	if res.LastUsed >= 0 {
+		fmt.Printf("Highest used account number: %d\n", res.LastUsed)
+	}
 }
@@ -1,9 +1,12 @@
 package pkg
 
 import (
+	"crypto/ecdsa"
 	"errors"
+	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/shopspring/decimal"
 )
 
@@ -40,6 +43,39 @@ func AmountToWei(input string) (*big.Int, error) {
 	return result, nil
 }
 
+// Parses decimal amount of gwei
+func GweiToWei(input string) (*big.Int, error) {
+	value, err := decimal.NewFromString(input)
+	if err != nil {
+		return nil, err
+	}
+
+	wei := value.Shift(9)
+	if !wei.Equal(wei.Truncate(0)) || wei.Sign() < 0 {
+		return nil, fmt.Errorf("Invalid gwei amount %s", input)
+	}
+
+	result, done := new(big.Int).SetString(wei.String(), 10)
+	if !done {
+		return nil, errors.New("Error parsing gwei amount")
+	}
+
+	return result, nil
+}
+
+func GweiString(input *big.Int) string {
+	return BigToDecimal(input).Shift(-9).String()
+}
+
+func GetPrivateKey(input string) (*ecdsa.PrivateKey, error) {
+	key, err := crypto.HexToECDSA(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
 func Units(wei bool) string {
 	if wei {
 		return "wei"
@@ -0,0 +1,313 @@
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	VaultVersion = 1
+
+	// Vault entry types
+	VaultXprv     = "xprv"
+	VaultXpub     = "xpub"
+	VaultMnemonic = "mnemonic"
+	VaultPrv      = "prv"
+
+	// Scrypt parameters (N = 2^17, ~128 MB of memory)
+	vaultScryptN = 1 << 17
+	vaultScryptR = 8
+	vaultScryptP = 1
+)
+
+var VaultTypes = []string{VaultXprv, VaultXpub, VaultMnemonic, VaultPrv}
+
+type VaultEntry struct {
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Secret     string    `json:"secret"`
+	Passphrase string    `json:"passphrase,omitempty"` // BIP-39 passphrase
+	Created    time.Time `json:"created"`
+}
+
+// Encrypted vault file: scrypt KDF + AES-256-GCM
+type vaultFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	CipherText string `json:"ciphertext"`
+}
+
+type Vault struct {
+	Entries  []VaultEntry
+	path     string
+	password string
+}
+
+func DefaultVaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "vault.json"
+	}
+
+	return filepath.Join(home, ".ethereum-hd-tools", "vault.json")
+}
+
+func CreateVault(path, password string) (*Vault, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("Vault %s already exists", path)
+	}
+
+	if len(password) == 0 {
+		return nil, errors.New("Vault password should not be empty")
+	}
+
+	v := &Vault{Entries: []VaultEntry{}, path: path, password: password}
+	return v, v.Save()
+}
+
+func OpenVault(path, password string) (*Vault, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := vaultFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if file.Version != VaultVersion || file.KDF != "scrypt" || file.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("Unsupported vault format (version %d, %s, %s)", file.Version, file.KDF, file.Cipher)
+	}
+
+	salt, err := hex.DecodeString(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := hex.DecodeString(file.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherText, err := hex.DecodeString(file.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := vaultCipher(password, salt, file.N, file.R, file.P)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("Invalid vault nonce")
+	}
+
+	plainText, err := aead.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return nil, errors.New("Could not decrypt vault with given password")
+	}
+
+	v := &Vault{Entries: []VaultEntry{}, path: path, password: password}
+	if err := json.Unmarshal(plainText, &v.Entries); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Reads vault password from source (prompt if empty) and opens vault
+func UnlockVault(path, passwordSource string) (*Vault, error) {
+	if len(path) == 0 {
+		path = DefaultVaultPath()
+	}
+
+	if len(passwordSource) == 0 {
+		passwordSource = "prompt"
+	}
+
+	password, err := ReadSecret(passwordSource, "Enter vault password: ")
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenVault(path, password)
+}
+
+func UnlockVaultEntry(path, passwordSource, name string) (*VaultEntry, error) {
+	vault, err := UnlockVault(path, passwordSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return vault.Get(name)
+}
+
+// Encrypts entries with fresh salt and nonce, replaces vault file atomically
+func (v *Vault) Save() error {
+	plainText, err := json.Marshal(v.Entries)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, err := vaultCipher(v.password, salt, vaultScryptN, vaultScryptR, vaultScryptP)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	file := vaultFile{
+		Version:    VaultVersion,
+		KDF:        "scrypt",
+		N:          vaultScryptN,
+		R:          vaultScryptR,
+		P:          vaultScryptP,
+		Salt:       hex.EncodeToString(salt),
+		Cipher:     "aes-256-gcm",
+		Nonce:      hex.EncodeToString(nonce),
+		CipherText: hex.EncodeToString(aead.Seal(nil, nonce, plainText, nil)),
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := v.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, v.path)
+}
+
+func (v *Vault) Get(name string) (*VaultEntry, error) {
+	for i := range v.Entries {
+		if v.Entries[i].Name == name {
+			return &v.Entries[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Key %q not found in vault", name)
+}
+
+func (v *Vault) Add(entry VaultEntry) error {
+	if len(entry.Name) == 0 {
+		return errors.New("Vault entry name should not be empty")
+	}
+
+	if _, err := v.Get(entry.Name); err == nil {
+		return fmt.Errorf("Key %q already exists in vault", entry.Name)
+	}
+
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	v.Entries = append(v.Entries, entry)
+	return nil
+}
+
+func (v *Vault) Remove(name string) error {
+	for i := range v.Entries {
+		if v.Entries[i].Name == name {
+			v.Entries = append(v.Entries[:i], v.Entries[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Key %q not found in vault", name)
+}
+
+func (entry VaultEntry) Validate() error {
+	switch entry.Type {
+	case VaultXprv, VaultXpub:
+		key, err := hdkeychain.NewKeyFromString(entry.Secret)
+		if err != nil {
+			return err
+		}
+
+		if key.IsPrivate() != (entry.Type == VaultXprv) {
+			return fmt.Errorf("Key doesn't match vault entry type %s", entry.Type)
+		}
+	case VaultMnemonic:
+		if _, err := MasterFromMnemonic(entry.Secret, entry.Passphrase); err != nil {
+			return err
+		}
+	case VaultPrv:
+		if _, err := GetPrivateKey(entry.Secret); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown vault entry type %q", entry.Type)
+	}
+
+	return nil
+}
+
+// Returns extended key of entry (master key for mnemonics)
+func (entry VaultEntry) ExtendedKey() (*hdkeychain.ExtendedKey, error) {
+	switch entry.Type {
+	case VaultXprv, VaultXpub:
+		return hdkeychain.NewKeyFromString(entry.Secret)
+	case VaultMnemonic:
+		return MasterFromMnemonic(entry.Secret, entry.Passphrase)
+	}
+
+	return nil, fmt.Errorf("Vault entry %q (%s) is not an extended key or mnemonic", entry.Name, entry.Type)
+}
+
+func (entry VaultEntry) PrivateKey() (*ecdsa.PrivateKey, error) {
+	if entry.Type != VaultPrv {
+		return nil, fmt.Errorf("Vault entry %q (%s) is not a private key", entry.Name, entry.Type)
+	}
+
+	return GetPrivateKey(entry.Secret)
+}
+
+func vaultCipher(password string, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, n, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
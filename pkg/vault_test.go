@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testVaultPassword = "correct horse battery staple"
+
+// Vault with single mnemonic entry "main"
+func testVault(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vault.json")
+	vault, err := CreateVault(path, testVaultPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vault.Add(VaultEntry{Name: "main", Type: VaultMnemonic, Secret: testMnemonic}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vault.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := testVault(t)
+	t.Setenv("TEST_VAULT_PASSWORD", testVaultPassword)
+	entry, err := UnlockVaultEntry(path, "env:TEST_VAULT_PASSWORD", "main")
+	if err != nil {
+		t.Fatalf("unlock error: %v", err)
+	}
+
+	if entry.Type != VaultMnemonic || entry.Secret != testMnemonic {
+		t.Errorf("entry %s (%s) changed after round trip", entry.Name, entry.Type)
+	}
+
+	if _, err := UnlockVaultEntry(path, "env:TEST_VAULT_PASSWORD", "other"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected missing entry error, got %v", err)
+	}
+
+	if _, err := CreateVault(path, testVaultPassword); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected error for existing vault, got %v", err)
+	}
+}
+
+func TestVaultWrongPassword(t *testing.T) {
+	path := testVault(t)
+	if _, err := OpenVault(path, testVaultPassword+"!"); err == nil || !strings.Contains(err.Error(), "Could not decrypt vault") {
+		t.Errorf("expected decryption error, got %v", err)
+	}
+}
+
+func TestVaultTampered(t *testing.T) {
+	path := testVault(t)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := vaultFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip last hex digit of ciphertext (authentication tag)
+	last := file.CipherText[len(file.CipherText)-1]
+	flipped := byte('0')
+	if last == '0' {
+		flipped = '1'
+	}
+	file.CipherText = file.CipherText[:len(file.CipherText)-1] + string(flipped)
+
+	data, err = json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenVault(path, testVaultPassword); err == nil || !strings.Contains(err.Error(), "Could not decrypt vault") {
+		t.Errorf("expected decryption error, got %v", err)
+	}
+}
+
+func TestVaultAdd(t *testing.T) {
+	vault := &Vault{Entries: []VaultEntry{}}
+	if err := vault.Add(VaultEntry{Name: "main", Type: VaultMnemonic, Secret: testMnemonic}); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		entry VaultEntry
+		err   string // Expected error substring
+	}{
+		{"duplicate name", VaultEntry{Name: "main", Type: VaultPrv, Secret: "0x01"}, `Key "main" already exists`},
+		{"empty name", VaultEntry{Type: VaultPrv, Secret: "0x01"}, "name should not be empty"},
+		{"unknown type", VaultEntry{Name: "seed", Type: "seed", Secret: testMnemonic}, "Unknown vault entry type"},
+		{"invalid mnemonic", VaultEntry{Name: "seed", Type: VaultMnemonic, Secret: "abandon abandon"}, ""},
+		{"invalid private key", VaultEntry{Name: "key", Type: VaultPrv, Secret: "0xzz"}, ""},
+	}
+
+	for _, test := range tests {
+		err := vault.Add(test.entry)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+
+	if len(vault.Entries) != 1 {
+		t.Errorf("vault has %d entries, want 1", len(vault.Entries))
+	}
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var networks = []*chaincfg.Params{
+	&chaincfg.MainNetParams,
+	&chaincfg.TestNet3Params,
+	&chaincfg.RegressionNetParams,
+	&chaincfg.SimNetParams,
+}
+
+type KeyInfo struct {
+	Version           []byte
+	Network           string
+	Private           bool
+	Depth             uint8
+	Fingerprint       uint32
+	ParentFingerprint uint32
+	ChildNumber       uint32
+	PublicKey         string // Compressed, hex-encoded
+	Address           string // Ethereum address of the key itself
+}
+
+// Decodes serialized fields, which are not exposed by hdkeychain
+func InspectKey(key *hdkeychain.ExtendedKey) (*KeyInfo, error) {
+	// version (4) || depth (1) || parent fingerprint (4)) || child num (4) || ...
+	serialized := base58.Decode(key.String())
+	if len(serialized) < 13 {
+		return nil, hdkeychain.ErrInvalidKeyLen
+	}
+
+	pub, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	compressed := pub.SerializeCompressed()
+	info := &KeyInfo{
+		Version:           serialized[:4],
+		Network:           "unknown",
+		Private:           key.IsPrivate(),
+		Depth:             key.Depth(),
+		Fingerprint:       binary.BigEndian.Uint32(btcutil.Hash160(compressed)[:4]),
+		ParentFingerprint: key.ParentFingerprint(),
+		ChildNumber:       binary.BigEndian.Uint32(serialized[9:13]),
+		PublicKey:         hex.EncodeToString(compressed),
+		Address:           crypto.PubkeyToAddress(*pub.ToECDSA()).Hex(),
+	}
+
+	for _, net := range networks {
+		if bytes.Equal(info.Version, net.HDPrivateKeyID[:]) || bytes.Equal(info.Version, net.HDPublicKeyID[:]) {
+			info.Network = net.Name
+		}
+	}
+
+	return info, nil
+}
+
+func (info *KeyInfo) Print() {
+	kind := "public"
+	if info.Private {
+		kind = "private"
+	}
+
+	fmt.Printf("Type: extended %s key\n", kind)
+	fmt.Printf("Version: %x (%s)\n", info.Version, info.Network)
+	fmt.Printf("Depth: %d\n", info.Depth)
+	fmt.Printf("Fingerprint: %08x\n", info.Fingerprint)
+	fmt.Printf("Parent fingerprint: %08x\n", info.ParentFingerprint)
+	fmt.Printf("Child number: %s (%d)\n", formatChild(info.ChildNumber), info.ChildNumber)
+	fmt.Printf("Public key: %s\n", info.PublicKey)
+	fmt.Printf("Address: %s\n", info.Address)
+}
+
+func formatChild(child uint32) string {
+	if child >= hdkeychain.HardenedKeyStart {
+		return fmt.Sprintf("%d'", child-hdkeychain.HardenedKeyStart)
+	}
+
+	return fmt.Sprintf("%d", child)
+}
+
+// Derives child extended key at concrete path, key depth determines
+// which path segments are already applied to it
+func DeriveChildKey(key *hdkeychain.ExtendedKey, path Path) (*hdkeychain.ExtendedKey, error) {
+	depth := int(key.Depth())
+	if depth > len(path) {
+		return nil, fmt.Errorf("Key depth %d is beyond path %s", depth, path.String())
+	}
+
+	return path.Derive(key, depth, len(path), 0)
+}